@@ -2,7 +2,6 @@ package akashi
 
 import (
 	"context"
-	"fmt"
 	"hapoon/go-akashi/pkg/akashi"
 	"log"
 	"os"
@@ -29,11 +28,27 @@ var alertCmd = &cobra.Command{
 			log.Fatalln(err)
 			os.Exit(1)
 		}
-		fmt.Println("企業ID:", res.LoginCompanyCode)
-		fmt.Println("従業員ID:", res.StaffID)
-		fmt.Println("アラート件数:", res.Count, "件")
-		for _, alert := range res.Alerts {
-			fmt.Println(alert.Month, "月", alert.Date, "日", alert.AlertType)
+		views := make([]alertView, 0, len(res.Alerts))
+		for _, v := range res.Alerts {
+			views = append(views, alertView{
+				StaffID:       res.StaffID,
+				Month:         v.Month,
+				Date:          v.Date,
+				AlertType:     int(v.AlertType),
+				AlertTypeName: v.AlertType.String(),
+			})
+		}
+		if err := out.render(views); err != nil {
+			log.Fatalln(err)
 		}
 	},
 }
+
+// alertView アラート情報の出力形式
+type alertView struct {
+	StaffID       int    `json:"staff_id"`
+	Month         string `json:"month"`
+	Date          string `json:"date"`
+	AlertType     int    `json:"alert_type"`
+	AlertTypeName string `json:"alert_type_name"`
+}
@@ -0,0 +1,44 @@
+package akashi
+
+import (
+	"context"
+	"log"
+	"path/filepath"
+
+	"hapoon/go-akashi/pkg/akashi"
+	"hapoon/go-akashi/pkg/akashi/calendar"
+)
+
+// loadCalendar 設定ファイルのcalendarで指定した会社カレンダーを読み込む
+// 未指定の場合は国民の祝日と土日を休日とする。相対パスは設定ファイルのディレクトリから解決する
+func loadCalendar() (*calendar.Calendar, error) {
+	cal := calendar.New()
+	if cfg == nil || cfg.Calendar == "" {
+		return cal, nil
+	}
+	path := cfg.Calendar
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(filepath.Dir(configPath), path)
+	}
+	if err := cal.LoadFile(path); err != nil {
+		return nil, err
+	}
+	return cal, nil
+}
+
+// staffCalendar アクセストークンの従業員の所属組織のカレンダーを返す
+// 従業員情報を取得できない場合は会社のカレンダーを返す
+func staffCalendar(cal *calendar.Calendar) *calendar.Calendar {
+	res, err := akashi.GetStaff(context.Background(), akashi.GetStaffParam{
+		LoginCompanyCode: loginCompanyCode,
+		Token:            accessToken,
+	})
+	if err != nil {
+		log.Printf("using the company calendar: cannot get the staff's organization: %v", err)
+		return cal
+	}
+	if len(res.Staffs) == 0 {
+		return cal
+	}
+	return cal.ForStaff(res.Staffs[0])
+}
@@ -0,0 +1,223 @@
+package akashi
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"hapoon/go-akashi/pkg/akashi"
+	"hapoon/go-akashi/pkg/akashi/attendance"
+	"hapoon/go-akashi/pkg/akashi/calendar"
+	"hapoon/go-akashi/pkg/akashi/compliance"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	// compliance check
+	complianceMonth   string
+	yearToDate        bool
+	disabledRules     []string
+	failOn            string
+	complianceDayHour int
+)
+
+func init() {
+	complianceCheckCmd.Flags().StringVar(&complianceMonth, "month", "this-month", "Month to check (YYYY-MM, this-month, last-month)")
+	complianceCheckCmd.Flags().BoolVar(&yearToDate, "year-to-date", false, "Also fetch stamps from the start of the 36-agreement year for the yearly cap")
+	complianceCheckCmd.Flags().StringSliceVar(&disabledRules, "disable", nil, "Rules to skip")
+	complianceCheckCmd.Flags().StringVar(&failOn, "fail-on", "error", "Exit with status 1 if a violation of this severity or higher is found (info, warning, error, none)")
+	complianceCheckCmd.Flags().IntVar(&complianceDayHour, "day-change-hour", 0, "Stamps before this hour belong to the previous day")
+	complianceCmd.AddCommand(complianceCheckCmd)
+	rootCmd.AddCommand(complianceCmd)
+}
+
+var complianceCmd = &cobra.Command{
+	Use:   "compliance",
+	Short: "Labor-rule compliance checks",
+	Long:  "Labor-rule compliance checks",
+	Run: func(cmd *cobra.Command, args []string) {
+		// このコマンド単体では動作しないのでヘルプを表示する
+	},
+}
+
+var complianceCheckCmd = &cobra.Command{
+	Use:   "check",
+	Short: "勤怠の法令チェック",
+	Long: `打刻から勤怠記録を作成し、次のルールで検査します。
+
+  break                 労働時間6時間超で45分、8時間超で60分の休憩
+  daily-overtime        1日の法定労働時間(8時間)超
+  weekly-overtime       1週の法定労働時間(40時間)超
+  monthly-overtime-cap  36協定の1か月の時間外労働の上限(45時間)超
+  yearly-overtime-cap   36協定の1年の時間外労働の上限(360時間)超
+  interval              勤務間インターバル(11時間)未満
+  holiday-work          会社カレンダー(calendar)の休日の勤務
+
+週の法定労働時間は休日や休業日があっても変わりません。
+
+基準は設定ファイルのcomplianceで変更できます。
+  compliance:
+    monthly_overtime_cap: 45h
+    year_start_month: 4
+    disabled: [interval]`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		var threshold compliance.Severity
+		if failOn != "none" {
+			s, err := compliance.ParseSeverity(failOn)
+			if err != nil {
+				log.Fatalln("--fail-on:", err)
+			}
+			threshold = s
+		}
+		if complianceDayHour < 0 || complianceDayHour > 23 {
+			log.Fatalln("--day-change-hour must be between 0 and 23")
+		}
+		month, err := parseDateExpr(complianceMonth, time.Now(), akashi.Location)
+		if err != nil {
+			log.Fatalln("--month:", err)
+		}
+		if !month.isMonth() {
+			log.Fatalf("--month: %q is not a month", complianceMonth)
+		}
+		cal, err := loadCalendar()
+		if err != nil {
+			log.Fatalln(err)
+		}
+		rules, c, err := complianceRules(cfg.Compliance, disabledRules, staffCalendar(cal))
+		if err != nil {
+			log.Fatalln(err)
+		}
+
+		r := month
+		if yearToDate {
+			start := time.Date(month.Start.Year(), c.YearStartMonth, 1, 0, 0, 0, 0, akashi.Location)
+			if start.After(month.Start) {
+				start = start.AddDate(-1, 0, 0)
+			}
+			r.Start = start
+		}
+		shift := time.Duration(complianceDayHour) * time.Hour
+		r.Start, r.End = r.Start.Add(shift), r.End.Add(shift)
+
+		res, err := fetchStampsAt(context.Background(), r, time.Now())
+		if err != nil {
+			log.Fatalln(err)
+		}
+		b := attendance.Builder{DayChangeHour: complianceDayHour, Location: akashi.Location}
+		violations := compliance.NewChecker(rules...).CheckStamps(b, res)
+
+		views := make([]violationView, 0, len(violations))
+		fail := false
+		for _, v := range violations {
+			if yearToDate && !inMonth(v, month) {
+				continue
+			}
+			views = append(views, violationView{
+				StaffID:  v.StaffID,
+				Date:     v.Date.Format("2006-01-02"),
+				Rule:     v.Rule,
+				Severity: v.Severity.String(),
+				Reason:   v.Reason,
+			})
+			if threshold != 0 && v.Severity >= threshold {
+				fail = true
+			}
+		}
+		if err := out.render(views); err != nil {
+			log.Fatalln(err)
+		}
+		if fail {
+			os.Exit(1)
+		}
+	},
+}
+
+// violationView 違反の出力形式
+type violationView struct {
+	StaffID  int    `json:"staff_id"`
+	Date     string `json:"date"`
+	Rule     string `json:"rule"`
+	Severity string `json:"severity"`
+	Reason   string `json:"reason"`
+}
+
+// inMonth 違反が対象の月に関するものか
+// 年単位の違反と月をまたぐ週の違反は対象の月に含める
+func inMonth(v compliance.Violation, month dateRange) bool {
+	switch v.Rule {
+	case "yearly-overtime-cap":
+		return true
+	case "weekly-overtime":
+		return v.Date.After(month.Start.AddDate(0, 0, -7))
+	}
+	return !v.Date.Before(month.Start)
+}
+
+// complianceRules 設定ファイルの基準と会社カレンダーから検査するルールを作成する
+func complianceRules(cc *ComplianceConfig, disabled []string, cal *calendar.Calendar) ([]compliance.Rule, compliance.Config, error) {
+	c := compliance.DefaultConfig()
+	c.Calendar = cal
+	if cc != nil {
+		durations := []struct {
+			name  string
+			value string
+			dst   *time.Duration
+		}{
+			{"daily_limit", cc.DailyLimit, &c.DailyLimit},
+			{"weekly_limit", cc.WeeklyLimit, &c.WeeklyLimit},
+			{"monthly_overtime_cap", cc.MonthlyOvertimeCap, &c.MonthlyOvertimeCap},
+			{"yearly_overtime_cap", cc.YearlyOvertimeCap, &c.YearlyOvertimeCap},
+			{"interval", cc.Interval, &c.Interval},
+		}
+		for _, d := range durations {
+			if d.value == "" {
+				continue
+			}
+			v, err := time.ParseDuration(d.value)
+			if err != nil {
+				return nil, c, fmt.Errorf("compliance.%s: %w", d.name, err)
+			}
+			*d.dst = v
+		}
+		if cc.WeekStart != "" {
+			wd, err := calendar.ParseWeekday(cc.WeekStart)
+			if err != nil {
+				return nil, c, fmt.Errorf("compliance.week_start: %w", err)
+			}
+			c.WeekStart = wd
+		}
+		if cc.YearStartMonth != 0 {
+			if cc.YearStartMonth < 1 || cc.YearStartMonth > 12 {
+				return nil, c, fmt.Errorf("compliance.year_start_month must be between 1 and 12")
+			}
+			c.YearStartMonth = time.Month(cc.YearStartMonth)
+		}
+		disabled = append(disabled, cc.Disabled...)
+	}
+
+	all := compliance.DefaultRules(c)
+	skip := map[string]bool{}
+	for _, name := range disabled {
+		known := false
+		for _, r := range all {
+			if r.Name() == name {
+				known = true
+			}
+		}
+		if !known {
+			return nil, c, fmt.Errorf("unknown rule %q", name)
+		}
+		skip[name] = true
+	}
+	var rules []compliance.Rule
+	for _, r := range all {
+		if !skip[r.Name()] {
+			rules = append(rules, r)
+		}
+	}
+	return rules, c, nil
+}
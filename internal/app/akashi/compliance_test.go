@@ -0,0 +1,35 @@
+package akashi
+
+import (
+	"testing"
+	"time"
+
+	"hapoon/go-akashi/pkg/akashi/calendar"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestComplianceRules(t *testing.T) {
+	rules, c, err := complianceRules(nil, nil, nil)
+	assert.NoError(t, err)
+	assert.Len(t, rules, 7)
+	assert.Equal(t, 45*time.Hour, c.MonthlyOvertimeCap)
+
+	rules, c, err = complianceRules(&ComplianceConfig{
+		MonthlyOvertimeCap: "80h",
+		WeekStart:          "Mon",
+		YearStartMonth:     1,
+		Disabled:           []string{"interval"},
+	}, []string{"daily-overtime"}, calendar.New())
+	assert.NoError(t, err)
+	assert.Len(t, rules, 5)
+	assert.Equal(t, 80*time.Hour, c.MonthlyOvertimeCap)
+	assert.Equal(t, time.Monday, c.WeekStart)
+	assert.Equal(t, time.January, c.YearStartMonth)
+	assert.NotNil(t, c.Calendar)
+
+	_, _, err = complianceRules(&ComplianceConfig{Interval: "11"}, nil, nil)
+	assert.Error(t, err)
+	_, _, err = complianceRules(nil, []string{"lunch"}, nil)
+	assert.Error(t, err)
+}
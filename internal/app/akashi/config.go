@@ -0,0 +1,136 @@
+package akashi
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	configCmd.AddCommand(configListCmd)
+	configCmd.AddCommand(configAddCmd)
+	configCmd.AddCommand(configUseCmd)
+	configCmd.AddCommand(configRemoveCmd)
+	rootCmd.AddCommand(configCmd)
+}
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Manage config profiles",
+	Long:  "Manage config profiles",
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		// 存在しないプロファイルが指定されていても追加できるようにする
+		if err := setup(false); err != nil && !errors.Is(err, errProfileNotFound) {
+			return err
+		}
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		// このコマンド単体では動作しないのでヘルプを表示する
+	},
+}
+
+var configListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "プロファイルの一覧",
+	Long:  "設定ファイルに登録されているプロファイルを一覧表示します。利用中のプロファイルには*が付きます。",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "CURRENT\tNAME\tCOMPANY CODE\tTIMEZONE\tBASE URL")
+		for _, name := range cfg.profileNames() {
+			p := cfg.Profiles[name]
+			mark := ""
+			if name == active.Profile {
+				mark = "*"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", mark, name, p.CompanyCode, p.Timezone, p.BaseURL)
+		}
+		return w.Flush()
+	},
+}
+
+var configAddCmd = &cobra.Command{
+	Use:   "add NAME",
+	Short: "プロファイルの追加・更新",
+	Long: `プロファイルを追加します。既に存在する場合は指定した項目のみ更新します。
+企業ID・アクセストークン等は --company-code, --token, --akashi-timezone, --base-url, --output で指定します。
+最初に追加したプロファイルが利用中のプロファイルになります。`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := args[0]
+		p, ok := cfg.Profiles[name]
+		if !ok {
+			p = &Profile{}
+			cfg.Profiles[name] = p
+		}
+		p.CompanyCode = first(flagSettings.CompanyCode, p.CompanyCode)
+		p.Timezone = first(flagSettings.Timezone, p.Timezone)
+		p.BaseURL = first(flagSettings.BaseURL, p.BaseURL)
+		p.Output = first(flagSettings.Output, p.Output)
+		if cfg.CurrentProfile == "" {
+			cfg.CurrentProfile = name
+		}
+		if err := cfg.save(configPath); err != nil {
+			return err
+		}
+		if flagSettings.Token != "" {
+			if err := credentials.Set(name, Credential{Token: flagSettings.Token}); err != nil {
+				return err
+			}
+		}
+		if ok {
+			fmt.Println("updated profile", name)
+		} else {
+			fmt.Println("added profile", name)
+		}
+		return nil
+	},
+}
+
+var configUseCmd = &cobra.Command{
+	Use:   "use NAME",
+	Short: "利用するプロファイルの切り替え",
+	Long:  "以降のコマンドで利用するプロファイルを切り替えます。",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := args[0]
+		if _, ok := cfg.Profiles[name]; !ok {
+			return fmt.Errorf("profile %q not found", name)
+		}
+		cfg.CurrentProfile = name
+		if err := cfg.save(configPath); err != nil {
+			return err
+		}
+		fmt.Println("switched to profile", name)
+		return nil
+	},
+}
+
+var configRemoveCmd = &cobra.Command{
+	Use:   "remove NAME",
+	Short: "プロファイルの削除",
+	Long:  "プロファイルを削除します。",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name := args[0]
+		if _, ok := cfg.Profiles[name]; !ok {
+			return fmt.Errorf("profile %q not found", name)
+		}
+		if err := credentials.Delete(name); err != nil {
+			return err
+		}
+		delete(cfg.Profiles, name)
+		if cfg.CurrentProfile == name {
+			cfg.CurrentProfile = ""
+		}
+		if err := cfg.save(configPath); err != nil {
+			return err
+		}
+		fmt.Println("removed profile", name)
+		return nil
+	},
+}
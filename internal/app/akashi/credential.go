@@ -0,0 +1,79 @@
+package akashi
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	// credential migrate
+	migrateTo string
+)
+
+func init() {
+	credentialMigrateCmd.Flags().StringVar(&migrateTo, "to", "", "Destination credential store (plain, encrypted)")
+	credentialCmd.AddCommand(credentialMigrateCmd)
+	rootCmd.AddCommand(credentialCmd)
+}
+
+var credentialCmd = &cobra.Command{
+	Use:   "credential",
+	Short: "Manage credential store",
+	Long: `アクセストークンの保存先を管理します。
+
+  plain      設定ファイルに平文で保存します(開発用、デフォルト)
+  encrypted  設定ファイルと同じディレクトリのcredentials.encにAES-256-GCMで暗号化して保存します
+             鍵はパスフレーズ(` + envPassphrase + `または対話入力)から導出するか、
+             CIでは` + envCredKey + `(base64でエンコードした32バイト)を利用します`,
+	Run: func(cmd *cobra.Command, args []string) {
+		// このコマンド単体では動作しないのでヘルプを表示する
+	},
+}
+
+var credentialMigrateCmd = &cobra.Command{
+	Use:   "migrate --to STORE",
+	Short: "アクセストークンの保存先の移行",
+	Long: `すべてのプロファイルのアクセストークンを現在の保存先から --to で指定した保存先に移し、
+以降はその保存先を利用します。移行元からはアクセストークンを削除します。`,
+	Args: cobra.NoArgs,
+	Annotations: map[string]string{
+		annotationNoCredential: "true",
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if migrateTo == "" {
+			return errors.New("--to must be set")
+		}
+		from := cfg.CredentialStore
+		if from == "" {
+			from = credentialStorePlain
+		}
+		if from == migrateTo {
+			return fmt.Errorf("credential store is already %s", migrateTo)
+		}
+		dst, err := newCredentialStore(cfg, configPath, migrateTo, os.Getenv)
+		if err != nil {
+			return err
+		}
+		// 移行元から削除する前に保存先の切り替えを設定ファイルに保存する
+		names, err := migrateCredentials(credentials, dst, func() error {
+			prev := cfg.CredentialStore
+			cfg.CredentialStore = migrateTo
+			if err := cfg.save(configPath); err != nil {
+				cfg.CredentialStore = prev
+				return err
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, name := range names {
+			fmt.Println("migrated profile", name)
+		}
+		fmt.Printf("credential store: %s -> %s\n", from, migrateTo)
+		return nil
+	},
+}
@@ -0,0 +1,420 @@
+package akashi
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/term"
+)
+
+// 認証情報の保存先
+const (
+	// credentialStorePlain 設定ファイルに平文で保存する(開発用)
+	credentialStorePlain = "plain"
+	// credentialStoreEncrypted 設定ファイルとは別の暗号化したファイルに保存する
+	credentialStoreEncrypted = "encrypted"
+
+	// credentialFileName 暗号化した認証情報のファイル名
+	credentialFileName = "credentials.enc"
+)
+
+// errCredentialNotFound プロファイルの認証情報が保存されていない
+var errCredentialNotFound = errors.New("credential not found")
+
+// Credential プロファイルごとの認証情報
+type Credential struct {
+	Token     string     `json:"token"`                // アクセストークン
+	ExpiredAt *time.Time `json:"expired_at,omitempty"` // アクセストークンの有効期限
+}
+
+// CredentialStore 認証情報の保存先
+type CredentialStore interface {
+	// Get profileの認証情報を返す。保存されていない場合はerrCredentialNotFound
+	Get(profile string) (Credential, error)
+	// Set profileの認証情報を保存する
+	Set(profile string, c Credential) error
+	// Delete profileの認証情報を削除する
+	Delete(profile string) error
+	// Profiles 認証情報が保存されているプロファイル名を昇順で返す
+	Profiles() ([]string, error)
+}
+
+// newCredentialStore 設定ファイルで指定された保存先を返す
+func newCredentialStore(cfg *Config, configPath, name string, getenv func(string) string) (CredentialStore, error) {
+	switch name {
+	case "", credentialStorePlain:
+		return &plainStore{cfg: cfg, path: configPath}, nil
+	case credentialStoreEncrypted:
+		path := cfg.CredentialFile
+		if path == "" {
+			path = filepath.Join(filepath.Dir(configPath), credentialFileName)
+		}
+		return &encryptedStore{
+			path:       path,
+			key:        getenv(envCredKey),
+			passphrase: passphraseReader(getenv),
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown credential store %q (plain, encrypted)", name)
+	}
+}
+
+// plainStore 設定ファイルのプロファイルに平文で保存する
+type plainStore struct {
+	cfg  *Config
+	path string
+}
+
+// Get implements CredentialStore
+func (s *plainStore) Get(profile string) (Credential, error) {
+	p, ok := s.cfg.Profiles[profile]
+	if !ok || p.Token == "" {
+		return Credential{}, fmt.Errorf("%w: %s", errCredentialNotFound, profile)
+	}
+	return Credential{Token: p.Token, ExpiredAt: p.TokenExpiredAt}, nil
+}
+
+// Set implements CredentialStore
+func (s *plainStore) Set(profile string, c Credential) error {
+	p := s.cfg.activeProfile(profile)
+	p.Token = c.Token
+	p.TokenExpiredAt = c.ExpiredAt
+	return s.cfg.save(s.path)
+}
+
+// Delete implements CredentialStore
+func (s *plainStore) Delete(profile string) error {
+	p, ok := s.cfg.Profiles[profile]
+	if !ok {
+		return nil
+	}
+	p.Token = ""
+	p.TokenExpiredAt = nil
+	return s.cfg.save(s.path)
+}
+
+// Profiles implements CredentialStore
+func (s *plainStore) Profiles() ([]string, error) {
+	var names []string
+	for _, name := range s.cfg.profileNames() {
+		if s.cfg.Profiles[name].Token != "" {
+			names = append(names, name)
+		}
+	}
+	return names, nil
+}
+
+// 暗号化ファイルの鍵の導出方法
+const (
+	kdfPBKDF2 = "pbkdf2-sha256" // パスフレーズから導出する
+	kdfRaw    = "raw"           // AKASHI_CREDENTIAL_KEYをそのまま利用する
+)
+
+// pbkdf2Iterations 新しく作成するファイルのPBKDF2の反復回数
+var pbkdf2Iterations = 600000
+
+// encryptedAAD 暗号文と一緒に認証する追加データ
+var encryptedAAD = []byte("aka-cli credentials v1")
+
+// encryptedFile 暗号化した認証情報のファイル形式
+type encryptedFile struct {
+	Version    int    `json:"version"`
+	KDF        string `json:"kdf"`
+	Iterations int    `json:"iterations,omitempty"`
+	Salt       []byte `json:"salt,omitempty"`
+	Nonce      []byte `json:"nonce"`
+	Data       []byte `json:"data"`
+}
+
+// encryptedStore AES-256-GCMで暗号化したファイルに保存する
+//
+// 鍵はAKASHI_CREDENTIAL_KEY(base64でエンコードした32バイト、CI向け)か、
+// パスフレーズからPBKDF2-HMAC-SHA256で導出する。
+// ファイルは最初に利用したときに読み込むので、認証情報を使わないコマンドではパスフレーズを要求しない
+type encryptedStore struct {
+	path       string
+	key        string
+	passphrase func(confirm bool) (string, error)
+
+	loaded  bool
+	header  encryptedFile
+	derived []byte
+	creds   map[string]Credential
+}
+
+// Get implements CredentialStore
+func (s *encryptedStore) Get(profile string) (Credential, error) {
+	if err := s.load(); err != nil {
+		return Credential{}, err
+	}
+	c, ok := s.creds[profile]
+	if !ok {
+		return Credential{}, fmt.Errorf("%w: %s", errCredentialNotFound, profile)
+	}
+	return c, nil
+}
+
+// Set implements CredentialStore
+func (s *encryptedStore) Set(profile string, c Credential) error {
+	if err := s.load(); err != nil {
+		return err
+	}
+	s.creds[profile] = c
+	return s.save()
+}
+
+// Delete implements CredentialStore
+func (s *encryptedStore) Delete(profile string) error {
+	if err := s.load(); err != nil {
+		return err
+	}
+	if _, ok := s.creds[profile]; !ok {
+		return nil
+	}
+	delete(s.creds, profile)
+	return s.save()
+}
+
+// Profiles implements CredentialStore
+func (s *encryptedStore) Profiles() ([]string, error) {
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(s.creds))
+	for k := range s.creds {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// load ファイルを読み込んで復号する
+// ファイルが存在しない場合は新しい鍵を準備する
+func (s *encryptedStore) load() error {
+	if s.loaded {
+		return nil
+	}
+	b, err := ioutil.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		if err := s.newKey(); err != nil {
+			return err
+		}
+		s.creds = map[string]Credential{}
+		s.loaded = true
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var f encryptedFile
+	if err := json.Unmarshal(b, &f); err != nil {
+		return fmt.Errorf("%s: %w", s.path, err)
+	}
+	if f.Version != 1 {
+		return fmt.Errorf("%s: unsupported version %d", s.path, f.Version)
+	}
+	key, err := s.deriveKey(f, false)
+	if err != nil {
+		return err
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return err
+	}
+	plain, err := gcm.Open(nil, f.Nonce, f.Data, encryptedAAD)
+	if err != nil {
+		return fmt.Errorf("%s: failed to decrypt credentials: wrong passphrase or key", s.path)
+	}
+	creds := map[string]Credential{}
+	if err := json.Unmarshal(plain, &creds); err != nil {
+		return fmt.Errorf("%s: %w", s.path, err)
+	}
+	s.header = f
+	s.derived = key
+	s.creds = creds
+	s.loaded = true
+	return nil
+}
+
+// newKey 新しく作成するファイルの鍵を準備する
+func (s *encryptedStore) newKey() error {
+	f := encryptedFile{Version: 1, KDF: kdfRaw}
+	if s.key == "" {
+		f.KDF = kdfPBKDF2
+		f.Iterations = pbkdf2Iterations
+		f.Salt = make([]byte, 16)
+		if _, err := io.ReadFull(rand.Reader, f.Salt); err != nil {
+			return err
+		}
+	}
+	key, err := s.deriveKey(f, true)
+	if err != nil {
+		return err
+	}
+	s.header = f
+	s.derived = key
+	return nil
+}
+
+// deriveKey ファイルの鍵を導出する
+func (s *encryptedStore) deriveKey(f encryptedFile, confirm bool) ([]byte, error) {
+	switch f.KDF {
+	case kdfRaw:
+		if s.key == "" {
+			return nil, fmt.Errorf("%s is encrypted with a raw key: set %s", s.path, envCredKey)
+		}
+		key, err := base64.StdEncoding.DecodeString(s.key)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", envCredKey, err)
+		}
+		if len(key) != 32 {
+			return nil, fmt.Errorf("%s must be 32 bytes encoded in base64", envCredKey)
+		}
+		return key, nil
+	case kdfPBKDF2:
+		if f.Iterations <= 0 || len(f.Salt) == 0 {
+			return nil, fmt.Errorf("%s: invalid key derivation parameters", s.path)
+		}
+		pass, err := s.passphrase(confirm)
+		if err != nil {
+			return nil, err
+		}
+		if pass == "" {
+			return nil, errors.New("passphrase must be set")
+		}
+		return pbkdf2Key([]byte(pass), f.Salt, f.Iterations, 32), nil
+	default:
+		return nil, fmt.Errorf("%s: unknown kdf %q", s.path, f.KDF)
+	}
+}
+
+// save 認証情報を暗号化して書き込む
+func (s *encryptedStore) save() error {
+	plain, err := json.Marshal(s.creds)
+	if err != nil {
+		return err
+	}
+	gcm, err := newGCM(s.derived)
+	if err != nil {
+		return err
+	}
+	f := s.header
+	f.Nonce = make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, f.Nonce); err != nil {
+		return err
+	}
+	f.Data = gcm.Seal(nil, f.Nonce, plain, encryptedAAD)
+	b, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(s.path, b)
+}
+
+// newGCM AES-256-GCMを返す
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// pbkdf2Key PBKDF2-HMAC-SHA256 (RFC 8018)
+func pbkdf2Key(password, salt []byte, iter, keyLen int) []byte {
+	return pbkdf2.Key(password, salt, iter, keyLen, sha256.New)
+}
+
+// passphraseReader AKASHI_PASSPHRASEか端末からパスフレーズを読み込む
+func passphraseReader(getenv func(string) string) func(confirm bool) (string, error) {
+	var cached string
+	return func(confirm bool) (string, error) {
+		if p := getenv(envPassphrase); p != "" {
+			return p, nil
+		}
+		if cached != "" {
+			return cached, nil
+		}
+		if !isTerminal(os.Stdin) {
+			return "", fmt.Errorf("passphrase required: set %s", envPassphrase)
+		}
+		p, err := readPassphrase("パスフレーズ: ")
+		if err != nil {
+			return "", err
+		}
+		if confirm {
+			again, err := readPassphrase("パスフレーズ(確認): ")
+			if err != nil {
+				return "", err
+			}
+			if p != again {
+				return "", errors.New("passphrases do not match")
+			}
+		}
+		cached = p
+		return p, nil
+	}
+}
+
+// readPassphrase 入力を表示せずに端末から1行読み込む
+func readPassphrase(prompt string) (string, error) {
+	fmt.Fprint(os.Stderr, prompt)
+	b, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// migrateCredentials srcの認証情報をすべてdstに移してsrcから削除する
+// dstへの保存が終わったらswitchStoreで保存先の切り替えを保存し、その後でsrcから削除する。
+// 切り替えまでに失敗した場合はdstに保存した認証情報を削除し、srcは変更しないので認証情報は失われない
+func migrateCredentials(src, dst CredentialStore, switchStore func() error) ([]string, error) {
+	names, err := src.Profiles()
+	if err != nil {
+		return nil, err
+	}
+	var copied []string
+	rollback := func(err error) ([]string, error) {
+		for _, name := range copied {
+			if derr := dst.Delete(name); derr != nil {
+				log.Println("failed to remove migrated credential:", name, derr)
+			}
+		}
+		return nil, err
+	}
+	for _, name := range names {
+		c, err := src.Get(name)
+		if err != nil {
+			return rollback(err)
+		}
+		if err := dst.Set(name, c); err != nil {
+			return rollback(err)
+		}
+		copied = append(copied, name)
+	}
+	if err := switchStore(); err != nil {
+		return rollback(err)
+	}
+	for _, name := range names {
+		if err := src.Delete(name); err != nil {
+			return nil, fmt.Errorf("removing %s from the previous credential store: %w", name, err)
+		}
+	}
+	return names, nil
+}
@@ -0,0 +1,138 @@
+package akashi
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPBKDF2Key(t *testing.T) {
+	// RFC 7914 11. Test Vectors for PBKDF2 with HMAC-SHA-256
+	got := pbkdf2Key([]byte("passwd"), []byte("salt"), 1, 64)
+	want := "55ac046e56e3089fec1691c22544b605f94185216dde0465e68b9d57c20dacbc" +
+		"49ca9cccf179b645991664b39d77ef317c71b845b1e30bd509112041d3a19783"
+	assert.Equal(t, want, hex.EncodeToString(got))
+}
+
+func TestEncryptedStore(t *testing.T) {
+	defer func(n int) { pbkdf2Iterations = n }(pbkdf2Iterations)
+	pbkdf2Iterations = 1000
+
+	dir, err := ioutil.TempDir("", "aka-cli")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	configPath := filepath.Join(dir, "config.yaml")
+
+	env := map[string]string{envPassphrase: "correct horse"}
+	getenv := func(k string) string { return env[k] }
+	store, err := newCredentialStore(&Config{}, configPath, credentialStoreEncrypted, getenv)
+	assert.NoError(t, err)
+
+	_, err = store.Get("work")
+	assert.True(t, errors.Is(err, errCredentialNotFound))
+
+	exp := time.Date(2026, 12, 31, 23, 59, 59, 0, time.UTC)
+	assert.NoError(t, store.Set("work", Credential{Token: "secret-token", ExpiredAt: &exp}))
+
+	path := filepath.Join(dir, credentialFileName)
+	fi, err := os.Stat(path)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), fi.Mode().Perm())
+	b, _ := ioutil.ReadFile(path)
+	assert.False(t, strings.Contains(string(b), "secret-token"))
+
+	// 別のプロセスから読み込む
+	store, _ = newCredentialStore(&Config{}, configPath, credentialStoreEncrypted, getenv)
+	c, err := store.Get("work")
+	assert.NoError(t, err)
+	assert.Equal(t, "secret-token", c.Token)
+	assert.True(t, exp.Equal(*c.ExpiredAt))
+
+	env[envPassphrase] = "wrong"
+	store, _ = newCredentialStore(&Config{}, configPath, credentialStoreEncrypted, getenv)
+	_, err = store.Get("work")
+	assert.Error(t, err)
+
+	// CI向けの鍵では別のファイルとして扱う
+	key := base64.StdEncoding.EncodeToString(make([]byte, 32))
+	env = map[string]string{envCredKey: key}
+	cfg := &Config{CredentialFile: filepath.Join(dir, "ci.enc")}
+	store, _ = newCredentialStore(cfg, configPath, credentialStoreEncrypted, getenv)
+	assert.NoError(t, store.Set("ci", Credential{Token: "ci-token"}))
+	store, _ = newCredentialStore(cfg, configPath, credentialStoreEncrypted, getenv)
+	c, err = store.Get("ci")
+	assert.NoError(t, err)
+	assert.Equal(t, "ci-token", c.Token)
+
+	env = map[string]string{}
+	store, _ = newCredentialStore(cfg, configPath, credentialStoreEncrypted, getenv)
+	_, err = store.Get("ci")
+	assert.Error(t, err)
+}
+
+func TestMigrateCredentials(t *testing.T) {
+	defer func(n int) { pbkdf2Iterations = n }(pbkdf2Iterations)
+	pbkdf2Iterations = 1000
+
+	dir, err := ioutil.TempDir("", "aka-cli")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	configPath := filepath.Join(dir, "config.yaml")
+	getenv := func(k string) string { return map[string]string{envPassphrase: "pass"}[k] }
+
+	cfg := &Config{Profiles: map[string]*Profile{
+		"work": {CompanyCode: "abc", Token: "work-token"},
+		"home": {CompanyCode: "def", Token: "home-token"},
+		"none": {CompanyCode: "ghi"},
+	}}
+	plain, _ := newCredentialStore(cfg, configPath, credentialStorePlain, getenv)
+	encrypted, _ := newCredentialStore(cfg, configPath, credentialStoreEncrypted, getenv)
+
+	// 保存先の切り替えに失敗した場合は移行しない
+	switchErr := errors.New("disk full")
+	_, err = migrateCredentials(plain, encrypted, func() error { return switchErr })
+	assert.True(t, errors.Is(err, switchErr))
+	assert.Equal(t, "work-token", cfg.Profiles["work"].Token)
+	_, err = encrypted.Get("work")
+	assert.True(t, errors.Is(err, errCredentialNotFound))
+
+	// 切り替えは移行元から削除する前に行う
+	switched := 0
+	switchStore := func() error {
+		switched++
+		names, _ := plain.Profiles()
+		assert.Len(t, names, 2, "source is intact when switching")
+		return nil
+	}
+	names, err := migrateCredentials(plain, encrypted, switchStore)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, switched)
+	assert.Equal(t, []string{"home", "work"}, names)
+	for _, p := range cfg.Profiles {
+		assert.Empty(t, p.Token)
+	}
+	b, _ := ioutil.ReadFile(configPath)
+	assert.False(t, strings.Contains(string(b), "work-token"))
+	c, err := encrypted.Get("work")
+	assert.NoError(t, err)
+	assert.Equal(t, "work-token", c.Token)
+
+	names, err = migrateCredentials(encrypted, plain, func() error { return nil })
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"home", "work"}, names)
+	assert.Equal(t, "home-token", cfg.Profiles["home"].Token)
+	_, err = encrypted.Get("home")
+	assert.True(t, errors.Is(err, errCredentialNotFound))
+}
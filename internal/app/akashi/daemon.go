@@ -0,0 +1,321 @@
+package akashi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+
+	"hapoon/go-akashi/pkg/akashi"
+	"hapoon/go-akashi/pkg/akashi/calendar"
+
+	"github.com/spf13/cobra"
+)
+
+// auditLogFileName 監査ログの既定のファイル名
+const auditLogFileName = "daemon-audit.log"
+
+const (
+	// daemonPollInterval 予定時刻まで待つ間に現在時刻を確認する間隔
+	// スリープからの復帰などで時計が進んだ場合に気付けるようにする
+	daemonPollInterval = time.Minute
+	// daemonLateTolerance 予定時刻からこの時間以上遅れた打刻は行わない
+	daemonLateTolerance = 10 * time.Minute
+	// daemonDedupeWindow 同じ種別の打刻がこの時間内にある場合は打刻しない
+	daemonDedupeWindow = 5 * time.Minute
+	// daemonActionTimeout 1回の打刻にかける時間の上限
+	daemonActionTimeout = time.Minute
+)
+
+func init() {
+	rootCmd.AddCommand(daemonCmd)
+}
+
+var daemonCmd = &cobra.Command{
+	Use:   "daemon",
+	Short: "スケジュールに従って打刻する",
+	Long: `設定ファイルのスケジュールに従ってフォアグラウンドで打刻を続けます。
+  daemon:
+    work_in: "09:00"
+    break_start: "12:00"  # 昼休憩は省略可
+    break_end: "13:00"
+    work_out: "18:00"
+    audit_log: ~/.config/aka-cli/daemon-audit.log  # 省略時は設定ファイルと同じディレクトリ
+会社カレンダー(calendar)の休日には打刻しません。
+打刻の前に今日の打刻から勤務状態を確認し、状態に合わない打刻
+(出勤済みの日の出勤など)や前後` + daemonDedupeWindow.String() + `以内にある同じ種別の打刻は行いません。
+起動時に過ぎている予定と、予定時刻から` + daemonLateTolerance.String() + `以上遅れた予定は打刻しません。
+すべての打刻・スキップ・エラーを監査ログにJSON Linesで記録します。
+有効期限が近づいたアクセストークンは再発行して利用中のプロファイルに保存します。
+SIGINT・SIGTERMを受け取ると実行中の打刻を終えてから終了します。`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		var dc DaemonConfig
+		if cfg != nil && cfg.Daemon != nil {
+			dc = *cfg.Daemon
+		}
+		path := dc.AuditLog
+		if path == "" {
+			path = filepath.Join(filepath.Dir(configPath), auditLogFileName)
+		}
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		defer f.Close()
+		audit := &auditLog{w: io.MultiWriter(f, os.Stderr)}
+
+		// 長時間動作するので期限が近づいたアクセストークンは再発行してプロファイルに保存する
+		useReissuingClient(audit.saveReissuedToken)
+		cal, err := loadCalendar()
+		if err != nil {
+			log.Fatalln(err)
+		}
+		sched, err := newSchedule(dc, staffCalendar(cal))
+		if err != nil {
+			log.Fatalln("daemon:", err)
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+		defer signal.Stop(sig)
+		go func() {
+			select {
+			case s := <-sig:
+				log.Printf("received %v; shutting down", s)
+				cancel()
+			case <-ctx.Done():
+			}
+		}()
+
+		d := &daemon{schedule: sched, audit: audit, now: time.Now}
+		if err := d.run(ctx); err != nil {
+			log.Fatalln(err)
+		}
+	},
+}
+
+// scheduleEntry 1日の予定の打刻
+type scheduleEntry struct {
+	hour, min int
+	typ       akashi.StampType
+}
+
+// schedule 営業日ごとに繰り返す打刻の予定
+type schedule struct {
+	entries  []scheduleEntry // 時刻順
+	calendar *calendar.Calendar
+	location *time.Location
+}
+
+// newSchedule 設定から打刻の予定を作成する
+// 出勤・退勤は必須で、昼休憩は開始と終了を合わせて指定する
+func newSchedule(dc DaemonConfig, cal *calendar.Calendar) (*schedule, error) {
+	if dc.WorkIn == "" {
+		return nil, errors.New("work_in must be set")
+	}
+	if dc.WorkOut == "" {
+		return nil, errors.New("work_out must be set")
+	}
+	if (dc.BreakStart == "") != (dc.BreakEnd == "") {
+		return nil, errors.New("break_start and break_end must be set together")
+	}
+	items := []struct {
+		name, value string
+		typ         akashi.StampType
+	}{
+		{"work_in", dc.WorkIn, akashi.StampTypeGoToWork},
+		{"break_start", dc.BreakStart, akashi.StampTypeBreak},
+		{"break_end", dc.BreakEnd, akashi.StampTypeBreakReturn},
+		{"work_out", dc.WorkOut, akashi.StampTypeLeaveWork},
+	}
+	s := &schedule{calendar: cal, location: akashi.Location}
+	for _, it := range items {
+		if it.value == "" {
+			continue
+		}
+		t, err := time.Parse("15:04", it.value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid time %q (want HH:MM)", it.name, it.value)
+		}
+		e := scheduleEntry{hour: t.Hour(), min: t.Minute(), typ: it.typ}
+		if n := len(s.entries); n > 0 {
+			prev := s.entries[n-1]
+			if e.hour*60+e.min <= prev.hour*60+prev.min {
+				return nil, fmt.Errorf("%s must be later than the %s time", it.name, prev.typ)
+			}
+		}
+		s.entries = append(s.entries, e)
+	}
+	return s, nil
+}
+
+// next afterより後の最初の予定の打刻を返す
+// 1年以内に営業日がない場合はfalse
+func (s *schedule) next(after time.Time) (time.Time, akashi.StampType, bool) {
+	t := after.In(s.location)
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, s.location)
+	for i := 0; i <= 366; i++ {
+		d := day.AddDate(0, 0, i)
+		if s.calendar != nil && !s.calendar.IsWorkday(d) {
+			continue
+		}
+		for _, e := range s.entries {
+			at := time.Date(d.Year(), d.Month(), d.Day(), e.hour, e.min, 0, 0, s.location)
+			if at.After(after) {
+				return at, e.typ, true
+			}
+		}
+	}
+	return time.Time{}, 0, false
+}
+
+// auditEntry 監査ログの1行
+type auditEntry struct {
+	Time        time.Time  `json:"time"`
+	Event       string     `json:"event"` // start, stop, stamp, skip, token, error
+	Type        int        `json:"type,omitempty"`
+	TypeName    string     `json:"type_name,omitempty"`
+	ScheduledAt *time.Time `json:"scheduled_at,omitempty"`
+	StampedAt   *time.Time `json:"stamped_at,omitempty"`
+	State       string     `json:"state,omitempty"` // 打刻前の勤務状態
+	Message     string     `json:"message,omitempty"`
+}
+
+// auditLog 監査ログ
+type auditLog struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// record 監査ログに1行追記する
+func (a *auditLog) record(e auditEntry) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	e.Time = e.Time.In(akashi.Location)
+	b, err := json.Marshal(e)
+	if err != nil {
+		log.Println("audit:", err)
+		return
+	}
+	if _, err := a.w.Write(append(b, '\n')); err != nil {
+		log.Println("audit:", err)
+	}
+}
+
+// saveReissuedToken 再発行したアクセストークンを保存して監査ログに記録する
+func (a *auditLog) saveReissuedToken(res akashi.PostTokenReissueResponse) error {
+	e := auditEntry{Time: time.Now(), Event: "token", Message: "reissued the access token"}
+	if res.ExpiredAt != nil {
+		e.Message += " (expires " + res.ExpiredAt.In(akashi.Location).Format(akashi.ReturnDateFormat) + ")"
+	}
+	err := saveReissuedToken(res)
+	if err != nil {
+		e.Event, e.Message = "error", e.Message+" but failed to save it: "+err.Error()
+	}
+	a.record(e)
+	return err
+}
+
+// daemon スケジュールに従って打刻する
+type daemon struct {
+	schedule *schedule
+	audit    *auditLog
+	now      func() time.Time
+}
+
+// run ctxが終了するまで予定の打刻を続ける
+func (d *daemon) run(ctx context.Context) error {
+	d.audit.record(auditEntry{Time: d.now(), Event: "start", Message: "profile " + active.Profile + ", company " + loginCompanyCode})
+	defer func() {
+		d.audit.record(auditEntry{Time: d.now(), Event: "stop"})
+	}()
+	after := d.now()
+	for {
+		at, typ, ok := d.schedule.next(after)
+		if !ok {
+			return errors.New("no workday within a year")
+		}
+		log.Printf("next: %s at %s", typ, at.Format(akashi.ReturnDateFormat))
+		for {
+			wait := at.Sub(d.now())
+			if wait <= 0 {
+				break
+			}
+			if wait > daemonPollInterval {
+				wait = daemonPollInterval
+			}
+			timer := time.NewTimer(wait)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return nil
+			case <-timer.C:
+			}
+		}
+		// 実行中の打刻はシグナルで中断しない
+		actx, cancel := context.WithTimeout(context.Background(), daemonActionTimeout)
+		d.audit.record(d.stamp(actx, typ, at))
+		cancel()
+		after = at
+		if now := d.now(); now.After(after) {
+			after = now
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+	}
+}
+
+// stamp 予定の打刻を行い監査ログの内容を返す
+// 予定時刻から遅れすぎた打刻、勤務状態に合わない打刻、重複する打刻は行わない
+func (d *daemon) stamp(ctx context.Context, typ akashi.StampType, scheduledAt time.Time) auditEntry {
+	now := d.now()
+	sched := scheduledAt.In(akashi.Location)
+	e := auditEntry{Time: now, Type: int(typ), TypeName: typ.String(), ScheduledAt: &sched}
+	if late := now.Sub(scheduledAt); late >= daemonLateTolerance {
+		e.Event, e.Message = "skip", fmt.Sprintf("missed by %s", late.Truncate(time.Second))
+		return e
+	}
+	day, err := today(ctx, now)
+	if err != nil {
+		e.Event, e.Message = "error", err.Error()
+		return e
+	}
+	state := day.State()
+	e.State = state.String()
+	if _, err := state.Apply(typ); err != nil {
+		e.Event, e.Message = "skip", err.Error()
+		return e
+	}
+	res, err := akashi.PostStamp(ctx, akashi.PostStampParam{
+		LoginCompanyCode: loginCompanyCode,
+		Token:            accessToken,
+		Type:             typ,
+		DedupeWindow:     daemonDedupeWindow,
+	})
+	if err != nil {
+		e.Event, e.Message = "error", err.Error()
+		return e
+	}
+	if res.StampedAt != nil {
+		stampedAt := res.StampedAt.In(akashi.Location)
+		e.StampedAt = &stampedAt
+	}
+	if res.Duplicate {
+		e.Event, e.Message = "skip", "already stamped"
+		return e
+	}
+	e.Event = "stamp"
+	return e
+}
@@ -0,0 +1,146 @@
+package akashi
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"hapoon/go-akashi/pkg/akashi"
+	"hapoon/go-akashi/pkg/akashi/akashitest"
+	"hapoon/go-akashi/pkg/akashi/calendar"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewSchedule(t *testing.T) {
+	_, err := newSchedule(DaemonConfig{WorkOut: "18:00"}, nil)
+	assert.EqualError(t, err, "work_in must be set")
+	_, err = newSchedule(DaemonConfig{WorkIn: "09:00", WorkOut: "18:00", BreakStart: "12:00"}, nil)
+	assert.EqualError(t, err, "break_start and break_end must be set together")
+	_, err = newSchedule(DaemonConfig{WorkIn: "9時", WorkOut: "18:00"}, nil)
+	assert.EqualError(t, err, `work_in: invalid time "9時" (want HH:MM)`)
+	_, err = newSchedule(DaemonConfig{WorkIn: "09:00", WorkOut: "18:00", BreakStart: "13:00", BreakEnd: "12:00"}, nil)
+	assert.EqualError(t, err, "break_end must be later than the 休憩入 time")
+}
+
+func TestScheduleNext(t *testing.T) {
+	cal := calendar.New()
+	// 2026/10/19(月)は会社の休業日
+	cal.AddClosure(time.Date(2026, 10, 19, 0, 0, 0, 0, akashi.Location), "創立記念日")
+	s, err := newSchedule(DaemonConfig{WorkIn: "09:00", BreakStart: "12:00", BreakEnd: "13:00", WorkOut: "18:00"}, cal)
+	assert.NoError(t, err)
+
+	at := func(day, hour, min int) time.Time { return time.Date(2026, 10, day, hour, min, 0, 0, akashi.Location) }
+	tests := []struct {
+		after time.Time
+		want  time.Time
+		typ   akashi.StampType
+	}{
+		{at(16, 8, 0), at(16, 9, 0), akashi.StampTypeGoToWork},
+		{at(16, 9, 0), at(16, 12, 0), akashi.StampTypeBreak},
+		{at(16, 12, 30), at(16, 13, 0), akashi.StampTypeBreakReturn},
+		{at(16, 13, 0), at(16, 18, 0), akashi.StampTypeLeaveWork},
+		// 金曜の退勤後は土日と休業日を飛ばして火曜の出勤
+		{at(16, 18, 0), at(20, 9, 0), akashi.StampTypeGoToWork},
+		{at(18, 10, 0), at(20, 9, 0), akashi.StampTypeGoToWork},
+	}
+	for _, tt := range tests {
+		got, typ, ok := s.next(tt.after)
+		assert.True(t, ok)
+		assert.True(t, tt.want.Equal(got), "after %v: got %v", tt.after, got)
+		assert.Equal(t, tt.typ, typ, "after %v", tt.after)
+	}
+
+	// 昼休憩なし
+	s, err = newSchedule(DaemonConfig{WorkIn: "09:30", WorkOut: "17:30"}, nil)
+	assert.NoError(t, err)
+	got, typ, _ := s.next(at(16, 10, 0))
+	assert.True(t, at(16, 17, 30).Equal(got))
+	assert.Equal(t, akashi.StampTypeLeaveWork, typ)
+}
+
+func TestDaemonStamp(t *testing.T) {
+	srv := akashitest.NewServer()
+	defer srv.Close()
+	srv.AddToken("abc", "tok", 1, time.Time{})
+	at := func(hour, min int) time.Time { return time.Date(2026, 10, 16, hour, min, 0, 0, akashi.Location) }
+	now := at(9, 0)
+	srv.Now = func() time.Time { return now }
+
+	defaultClient := akashi.DefaultClient
+	defer func() { akashi.DefaultClient = defaultClient }()
+	akashi.DefaultClient = srv.Client()
+	loginCompanyCode, accessToken = "abc", "tok"
+	defer func() { loginCompanyCode, accessToken = "", "" }()
+
+	d := &daemon{now: func() time.Time { return now }}
+	ctx := context.Background()
+
+	e := d.stamp(ctx, akashi.StampTypeGoToWork, at(9, 0))
+	assert.Equal(t, "stamp", e.Event, e.Message)
+	assert.Equal(t, "未出勤", e.State)
+
+	// 出勤済みのため2回目の出勤は打刻しない
+	now = at(9, 1)
+	e = d.stamp(ctx, akashi.StampTypeGoToWork, at(9, 0))
+	assert.Equal(t, "skip", e.Event)
+	assert.Equal(t, "勤務中のため出勤は打刻できません", e.Message)
+
+	// 休憩に入っていないため休憩戻は打刻しない
+	now = at(13, 0)
+	e = d.stamp(ctx, akashi.StampTypeBreakReturn, at(13, 0))
+	assert.Equal(t, "skip", e.Event)
+
+	// 予定時刻から遅れすぎた打刻は行わない
+	now = at(18, 30)
+	e = d.stamp(ctx, akashi.StampTypeLeaveWork, at(18, 0))
+	assert.Equal(t, "skip", e.Event)
+	assert.Equal(t, "missed by 30m0s", e.Message)
+
+	assert.Len(t, srv.Stamps("abc", 1), 1)
+
+	var buf bytes.Buffer
+	(&auditLog{w: &buf}).record(e)
+	assert.Contains(t, buf.String(), `"event":"skip"`)
+	assert.Contains(t, buf.String(), `"type_name":"退勤"`)
+}
+
+func TestDaemonReissueToken(t *testing.T) {
+	_, restore := useTokenProfile(t, plainCredentials)
+	defer restore()
+
+	srv := akashitest.NewServer()
+	defer srv.Close()
+	// 有効期限が近いトークン
+	exp := time.Now().Add(5 * time.Minute).Truncate(time.Second)
+	srv.AddToken("abc", "tok", 1, exp)
+	assert.NoError(t, credentials.Set("work", Credential{Token: "tok", ExpiredAt: &exp}))
+
+	defaultClient, defaultOptions := akashi.DefaultClient, clientOptions
+	defer func() { akashi.DefaultClient, clientOptions = defaultClient, defaultOptions }()
+	clientOptions = []akashi.Option{akashi.WithBaseURL(srv.URL)}
+	akashi.DefaultClient = akashi.NewClient(clientOptions...)
+	loginCompanyCode, accessToken = "abc", "tok"
+	defer func() { loginCompanyCode, accessToken = "", "" }()
+
+	var buf bytes.Buffer
+	audit := &auditLog{w: &buf}
+	ts := useReissuingClient(audit.saveReissuedToken)
+	d := &daemon{audit: audit, now: time.Now}
+
+	e := d.stamp(context.Background(), akashi.StampTypeGoToWork, time.Now())
+	assert.Equal(t, "stamp", e.Event, e.Message)
+	assert.Len(t, srv.Stamps("abc", 1), 1)
+
+	// 再発行したトークンをプロファイルに保存する
+	tok := ts.Current()
+	assert.NotEqual(t, "tok", tok.Value)
+	c, err := credentials.Get("work")
+	assert.NoError(t, err)
+	assert.Equal(t, tok.Value, c.Token)
+	if assert.NotNil(t, c.ExpiredAt) {
+		assert.True(t, tok.ExpiredAt.Equal(*c.ExpiredAt))
+	}
+	assert.Contains(t, buf.String(), `"event":"token"`)
+}
@@ -0,0 +1,151 @@
+package akashi
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"hapoon/go-akashi/pkg/akashi"
+)
+
+// dateRange 日付の範囲
+type dateRange struct {
+	Start time.Time // 開始日時
+	End   time.Time // 終了日時(この時刻を含む)
+}
+
+// dateExprHelp 指定できる日付の形式
+const dateExprHelp = "YYYY-MM-DD, YYYY/MM/DD, YYYY-MM, YYYY/MM, today, yesterday, this-week, last-week, this-month, last-month"
+
+// parseDateExpr 日付の表現をlocでの範囲に変換する
+//
+// 日付は1日、月(YYYY-MM)はその月全体、週は月曜日から日曜日を表す。
+// 従来のyyyymmddHHMMSS形式はその時刻ちょうどを表す
+func parseDateExpr(expr string, now time.Time, loc *time.Location) (dateRange, error) {
+	expr = strings.TrimSpace(expr)
+	now = now.In(loc)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	switch strings.ToLower(expr) {
+	case "":
+		return dateRange{}, errors.New("date must be set")
+	case "today":
+		return days(today, 1), nil
+	case "yesterday":
+		return days(today.AddDate(0, 0, -1), 1), nil
+	case "this-week":
+		return days(weekStart(today), 7), nil
+	case "last-week":
+		return days(weekStart(today).AddDate(0, 0, -7), 7), nil
+	case "this-month":
+		return months(today.Year(), today.Month(), loc), nil
+	case "last-month":
+		return months(today.Year(), today.Month()-1, loc), nil
+	}
+
+	for _, layout := range []string{"2006-01-02", "2006/01/02", "2006/1/2", "20060102"} {
+		if t, err := time.ParseInLocation(layout, expr, loc); err == nil {
+			return days(t, 1), nil
+		}
+	}
+	for _, layout := range []string{"2006-01", "2006/01", "2006/1"} {
+		if t, err := time.ParseInLocation(layout, expr, loc); err == nil {
+			return months(t.Year(), t.Month(), loc), nil
+		}
+	}
+	if t, err := time.ParseInLocation(akashi.DateFormat, expr, loc); err == nil {
+		return dateRange{Start: t, End: t}, nil
+	}
+	return dateRange{}, fmt.Errorf("invalid date %q: use %s", expr, dateExprHelp)
+}
+
+// resolveDateRange 開始・終了の指定から範囲を決定する
+//
+// どちらも指定がない場合は今日、片方だけ指定された場合はその表現が表す範囲とする
+func resolveDateRange(start, end string, now time.Time, loc *time.Location) (dateRange, error) {
+	if start == "" && end == "" {
+		start = "today"
+	}
+	var r dateRange
+	if start != "" {
+		s, err := parseDateExpr(start, now, loc)
+		if err != nil {
+			return dateRange{}, fmt.Errorf("--start-date: %w", err)
+		}
+		r = s
+	}
+	if end != "" {
+		e, err := parseDateExpr(end, now, loc)
+		if err != nil {
+			return dateRange{}, fmt.Errorf("--end-date: %w", err)
+		}
+		if start == "" {
+			r.Start = e.Start
+		}
+		r.End = e.End
+	}
+	if r.End.Before(r.Start) {
+		return dateRange{}, fmt.Errorf("--end-date (%s) must not be before --start-date (%s)",
+			r.End.Format(akashi.ReturnDateFormat), r.Start.Format(akashi.ReturnDateFormat))
+	}
+	return r, nil
+}
+
+// days startからn日間
+func days(start time.Time, n int) dateRange {
+	return dateRange{Start: start, End: start.AddDate(0, 0, n).Add(-time.Second)}
+}
+
+// months year年month月の1か月間
+func months(year int, month time.Month, loc *time.Location) dateRange {
+	start := time.Date(year, month, 1, 0, 0, 0, 0, loc)
+	return dateRange{Start: start, End: start.AddDate(0, 1, 0).Add(-time.Second)}
+}
+
+// isMonth rがちょうど1か月間か
+func (r dateRange) isMonth() bool {
+	m := months(r.Start.Year(), r.Start.Month(), r.Start.Location())
+	return r.Start.Equal(m.Start) && r.End.Equal(m.End)
+}
+
+// weekStart tを含む週の月曜日
+func weekStart(t time.Time) time.Time {
+	offset := (int(t.Weekday()) + 6) % 7
+	return t.AddDate(0, 0, -offset)
+}
+
+// timeExprHelp 指定できる日時の形式
+const timeExprHelp = "HH:MM[:SS], DATE HH:MM[:SS] (e.g. yesterday 18:30, 2026-10-01 09:05), RFC3339, YYYYMMDDHHMMSS"
+
+// parseTimeExpr 日時の表現をlocでの日時に変換する
+//
+// 時刻だけの場合は今日、日付と時刻の場合はparseDateExprの1日を表す表現とその時刻とする
+func parseTimeExpr(expr string, now time.Time, loc *time.Location) (time.Time, error) {
+	expr = strings.TrimSpace(expr)
+	if expr == "" {
+		return time.Time{}, errors.New("time must be set")
+	}
+	if t, err := time.Parse(time.RFC3339, expr); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation(akashi.DateFormat, expr, loc); err == nil {
+		return t, nil
+	}
+
+	date, clock := "today", expr
+	if fields := strings.Fields(expr); len(fields) == 2 {
+		date, clock = fields[0], fields[1]
+	} else if i := strings.Index(expr, "T"); i > 0 {
+		date, clock = expr[:i], expr[i+1:]
+	}
+	d, err := parseDateExpr(date, now, loc)
+	if err != nil || !d.End.Equal(days(d.Start, 1).End) {
+		return time.Time{}, fmt.Errorf("invalid time %q: use %s", expr, timeExprHelp)
+	}
+	for _, layout := range []string{"15:04", "15:04:05"} {
+		if c, err := time.Parse(layout, clock); err == nil {
+			return time.Date(d.Start.Year(), d.Start.Month(), d.Start.Day(), c.Hour(), c.Minute(), c.Second(), 0, loc), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q: use %s", expr, timeExprHelp)
+}
@@ -0,0 +1,91 @@
+package akashi
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveDateRange(t *testing.T) {
+	jst := time.FixedZone("JST", 9*60*60)
+	// 2026/09/16(水) 00:30 JST (UTCでは前日)
+	now := time.Date(2026, 9, 15, 15, 30, 0, 0, time.UTC)
+	day := func(y int, m time.Month, d int) time.Time { return time.Date(y, m, d, 0, 0, 0, 0, jst) }
+	end := func(y int, m time.Month, d int) time.Time { return time.Date(y, m, d, 23, 59, 59, 0, jst) }
+
+	tests := map[string]struct {
+		start, end string
+		want       dateRange
+		err        bool
+	}{
+		"default today":   {want: dateRange{day(2026, 9, 16), end(2026, 9, 16)}},
+		"today":           {start: "today", want: dateRange{day(2026, 9, 16), end(2026, 9, 16)}},
+		"yesterday":       {start: "Yesterday", want: dateRange{day(2026, 9, 15), end(2026, 9, 15)}},
+		"this-week":       {start: "this-week", want: dateRange{day(2026, 9, 14), end(2026, 9, 20)}},
+		"last-week":       {start: "last-week", want: dateRange{day(2026, 9, 7), end(2026, 9, 13)}},
+		"this-month":      {start: "this-month", want: dateRange{day(2026, 9, 1), end(2026, 9, 30)}},
+		"last-month":      {start: "last-month", want: dateRange{day(2026, 8, 1), end(2026, 8, 31)}},
+		"iso":             {start: "2026-09-01", want: dateRange{day(2026, 9, 1), end(2026, 9, 1)}},
+		"akashi style":    {start: "2026/9/1", end: "2026/09/15", want: dateRange{day(2026, 9, 1), end(2026, 9, 15)}},
+		"month":           {start: "2026-02", want: dateRange{day(2026, 2, 1), end(2026, 2, 28)}},
+		"end only":        {end: "2026/09", want: dateRange{day(2026, 9, 1), end(2026, 9, 30)}},
+		"month to date":   {start: "2026-08", end: "today", want: dateRange{day(2026, 8, 1), end(2026, 9, 16)}},
+		"legacy":          {start: "20260901090000", end: "20260901180000", want: dateRange{time.Date(2026, 9, 1, 9, 0, 0, 0, jst), time.Date(2026, 9, 1, 18, 0, 0, 0, jst)}},
+		"invalid":         {start: "2026-13-01", err: true},
+		"unknown keyword": {start: "next-week", err: true},
+		"reversed":        {start: "today", end: "yesterday", err: true},
+	}
+	for scenario, test := range tests {
+		r, err := resolveDateRange(test.start, test.end, now, jst)
+		if test.err {
+			assert.Error(t, err, scenario)
+			continue
+		}
+		if assert.NoError(t, err, scenario) {
+			assert.True(t, test.want.Start.Equal(r.Start), "%s: start %s", scenario, r.Start)
+			assert.True(t, test.want.End.Equal(r.End), "%s: end %s", scenario, r.End)
+		}
+	}
+}
+
+func TestParseTimeExpr(t *testing.T) {
+	jst := time.FixedZone("JST", 9*60*60)
+	now := time.Date(2026, 9, 15, 15, 30, 0, 0, time.UTC) // 2026/09/16 00:30 JST
+	at := func(d, h, m, s int) time.Time { return time.Date(2026, 9, d, h, m, s, 0, jst) }
+
+	tests := map[string]struct {
+		expr string
+		want time.Time
+		err  bool
+	}{
+		"clock":         {expr: "09:05", want: at(16, 9, 5, 0)},
+		"seconds":       {expr: "9:05:30", want: at(16, 9, 5, 30)},
+		"yesterday":     {expr: "yesterday 18:30", want: at(15, 18, 30, 0)},
+		"date":          {expr: "2026/09/01 09:05", want: at(1, 9, 5, 0)},
+		"iso local":     {expr: "2026-09-01T09:05:00", want: at(1, 9, 5, 0)},
+		"rfc3339":       {expr: "2026-09-01T09:05:00-04:00", want: at(1, 22, 5, 0)},
+		"legacy":        {expr: "20260901090500", want: at(1, 9, 5, 0)},
+		"date only":     {expr: "yesterday", err: true},
+		"week":          {expr: "this-week 09:00", err: true},
+		"invalid clock": {expr: "25:00", err: true},
+	}
+	for scenario, test := range tests {
+		got, err := parseTimeExpr(test.expr, now, jst)
+		if test.err {
+			assert.Error(t, err, scenario)
+			continue
+		}
+		if assert.NoError(t, err, scenario) {
+			assert.True(t, test.want.Equal(got), "%s: %s", scenario, got)
+		}
+	}
+}
+
+func TestDateRangeIsMonth(t *testing.T) {
+	jst := time.FixedZone("JST", 9*60*60)
+	assert.True(t, months(2026, 2, jst).isMonth())
+	assert.False(t, days(time.Date(2026, 9, 1, 0, 0, 0, 0, jst), 28).isMonth())
+	assert.False(t, dateRange{Start: time.Date(2026, 1, 1, 0, 0, 0, 0, jst), End: months(2026, 3, jst).End}.isMonth())
+	assert.False(t, days(time.Date(2026, 9, 2, 0, 0, 0, 0, jst), 30).isMonth())
+}
@@ -0,0 +1,276 @@
+package akashi
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+	"time"
+
+	"hapoon/go-akashi/pkg/akashi"
+
+	"gopkg.in/yaml.v2"
+)
+
+// 出力形式
+const (
+	outputTable    = "table"
+	outputJSON     = "json"
+	outputNDJSON   = "ndjson"
+	outputYAML     = "yaml"
+	outputCSV      = "csv"
+	outputTemplate = "template"
+)
+
+// outputFormats 指定できる出力形式
+var outputFormats = []string{outputTable, outputJSON, outputNDJSON, outputYAML, outputCSV, outputTemplate}
+
+var (
+	// outputFormat --output
+	outputFormat string
+	// outputTemplateText --template
+	outputTemplateText string
+
+	// out コマンドの結果を出力する
+	out *renderer
+)
+
+// renderer コマンドの結果を指定された形式で出力する
+//
+// 出力する値はフィールドにjsonタグを付けた構造体か、その配列。
+// フィールド名はjsonタグの名前をすべての形式で共通に利用する。
+// 配列の場合、table・csvは1要素を1行、ndjson・templateは1要素ずつ出力する
+type renderer struct {
+	w      io.Writer
+	format string
+	tmpl   *template.Template
+}
+
+// newRenderer 出力形式を検証してrendererを返す
+// textが指定された場合はformatに関わらずGoのテンプレートとして出力する
+func newRenderer(w io.Writer, format, text string) (*renderer, error) {
+	r := &renderer{w: w, format: format}
+	if text != "" {
+		r.format = outputTemplate
+	}
+	if r.format == "" {
+		r.format = outputTable
+	}
+	switch r.format {
+	case outputTable, outputJSON, outputNDJSON, outputYAML, outputCSV:
+	case outputTemplate:
+		if text == "" {
+			return nil, errors.New("--template must be set for template output")
+		}
+		t, err := template.New("output").Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid template: %w", err)
+		}
+		r.tmpl = t
+	default:
+		return nil, fmt.Errorf("unknown output format %q (%s)", format, strings.Join(outputFormats, ", "))
+	}
+	return r, nil
+}
+
+// render vを出力する
+func (r *renderer) render(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	list := rv.Kind() == reflect.Slice
+	var records []reflect.Value
+	if list {
+		for i := 0; i < rv.Len(); i++ {
+			records = append(records, rv.Index(i))
+		}
+	} else {
+		records = []reflect.Value{rv}
+	}
+
+	switch r.format {
+	case outputJSON:
+		if list && rv.IsNil() {
+			v = []struct{}{}
+		}
+		b, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(r.w, string(b))
+		return err
+	case outputNDJSON:
+		for _, rec := range records {
+			b, err := json.Marshal(rec.Interface())
+			if err != nil {
+				return err
+			}
+			if _, err := fmt.Fprintln(r.w, string(b)); err != nil {
+				return err
+			}
+		}
+		return nil
+	case outputYAML:
+		var doc interface{}
+		if list {
+			items := make([]yaml.MapSlice, 0, len(records))
+			for _, rec := range records {
+				items = append(items, yamlRecord(rec))
+			}
+			doc = items
+		} else {
+			doc = yamlRecord(rv)
+		}
+		b, err := yaml.Marshal(doc)
+		if err != nil {
+			return err
+		}
+		_, err = r.w.Write(b)
+		return err
+	case outputCSV:
+		w := csv.NewWriter(r.w)
+		if err := w.Write(columnNames(rv.Type())); err != nil {
+			return err
+		}
+		for _, rec := range records {
+			if err := w.Write(columnValues(rec, time.RFC3339)); err != nil {
+				return err
+			}
+		}
+		w.Flush()
+		return w.Error()
+	case outputTemplate:
+		for _, rec := range records {
+			if err := r.tmpl.Execute(r.w, rec.Interface()); err != nil {
+				return err
+			}
+			if _, err := fmt.Fprintln(r.w); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		w := tabwriter.NewWriter(r.w, 0, 4, 2, ' ', 0)
+		names := columnNames(rv.Type())
+		if !list {
+			// 1件の場合は項目ごとに1行で表示する
+			values := columnValues(rv, akashi.ReturnDateFormat)
+			for i, name := range names {
+				fmt.Fprintf(w, "%s:\t%s\n", name, values[i])
+			}
+			return w.Flush()
+		}
+		headers := make([]string, len(names))
+		for i, name := range names {
+			headers[i] = strings.ToUpper(name)
+		}
+		fmt.Fprintln(w, strings.Join(headers, "\t"))
+		for _, rec := range records {
+			fmt.Fprintln(w, strings.Join(columnValues(rec, akashi.ReturnDateFormat), "\t"))
+		}
+		return w.Flush()
+	}
+}
+
+// columnNames 構造体のフィールド名(jsonタグ)を返す
+func columnNames(t reflect.Type) []string {
+	if t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+	var names []string
+	for i := 0; i < t.NumField(); i++ {
+		if name, ok := fieldName(t.Field(i)); ok {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// columnValues 構造体のフィールドの値を文字列で返す
+// 日時はlayoutで表す
+func columnValues(v reflect.Value, layout string) []string {
+	var values []string
+	for i := 0; i < v.NumField(); i++ {
+		if _, ok := fieldName(v.Type().Field(i)); ok {
+			values = append(values, formatValue(v.Field(i), layout))
+		}
+	}
+	return values
+}
+
+// yamlRecord フィールドの順序を保ったままyamlに変換する
+func yamlRecord(v reflect.Value) yaml.MapSlice {
+	var m yaml.MapSlice
+	for i := 0; i < v.NumField(); i++ {
+		name, ok := fieldName(v.Type().Field(i))
+		if !ok {
+			continue
+		}
+		f := v.Field(i)
+		var value interface{}
+		switch {
+		case f.Kind() == reflect.Ptr && f.IsNil():
+			value = nil
+		case f.Kind() == reflect.Ptr:
+			value = formatValue(f, time.RFC3339)
+		case f.Type() == reflect.TypeOf(time.Time{}):
+			value = formatValue(f, time.RFC3339)
+		default:
+			value = f.Interface()
+		}
+		m = append(m, yaml.MapItem{Key: name, Value: value})
+	}
+	return m
+}
+
+// fieldName jsonタグの名前を返す。出力しないフィールドはfalse
+func fieldName(f reflect.StructField) (string, bool) {
+	if f.PkgPath != "" {
+		return "", false
+	}
+	tag := f.Tag.Get("json")
+	if tag == "-" {
+		return "", false
+	}
+	name := strings.Split(tag, ",")[0]
+	if name == "" {
+		name = f.Name
+	}
+	return name, true
+}
+
+// formatValue 値を文字列で表す
+func formatValue(v reflect.Value, layout string) string {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+	switch x := v.Interface().(type) {
+	case time.Time:
+		if x.IsZero() {
+			return ""
+		}
+		return x.Format(layout)
+	case float32:
+		return strconv.FormatFloat(float64(x), 'f', -1, 32)
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64)
+	default:
+		return fmt.Sprint(x)
+	}
+}
+
+// akTime AkTimeを出力用の日時に変換する
+func akTime(t *akashi.AkTime) *time.Time {
+	if t == nil || t.IsZero() {
+		return nil
+	}
+	v := t.In(akashi.Location)
+	return &v
+}
@@ -0,0 +1,98 @@
+package akashi
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testView struct {
+	ID       int        `json:"id"`
+	Name     string     `json:"name"`
+	At       *time.Time `json:"at"`
+	Ratio    float32    `json:"ratio"`
+	internal string
+	Ignored  string `json:"-"`
+}
+
+func TestRenderer(t *testing.T) {
+	at := time.Date(2026, 9, 1, 9, 0, 0, 0, time.FixedZone("JST", 9*60*60))
+	views := []testView{
+		{ID: 1, Name: "山田, 太郎", At: &at, Ratio: 0.5, internal: "x", Ignored: "y"},
+		{ID: 2, Name: "佐藤"},
+	}
+	tests := map[string]struct {
+		format   string
+		template string
+		v        interface{}
+		want     string
+	}{
+		"table": {
+			format: outputTable,
+			v: []testView{
+				{ID: 1, Name: "Yamada, Taro", At: &at, Ratio: 0.5},
+				{ID: 2, Name: "Sato"},
+			},
+			want: "ID  NAME          AT                   RATIO\n" +
+				"1   Yamada, Taro  2026/09/01 09:00:00  0.5\n" +
+				"2   Sato                               0\n",
+		},
+		"table single": {
+			v:    views[1],
+			want: "id:     2\nname:   佐藤\nat:     \nratio:  0\n",
+		},
+		"json": {
+			format: outputJSON,
+			v:      views[:1],
+			want:   "[\n  {\n    \"id\": 1,\n    \"name\": \"山田, 太郎\",\n    \"at\": \"2026-09-01T09:00:00+09:00\",\n    \"ratio\": 0.5\n  }\n]\n",
+		},
+		"json empty list": {
+			format: outputJSON,
+			v:      []testView(nil),
+			want:   "[]\n",
+		},
+		"ndjson": {
+			format: outputNDJSON,
+			v:      views,
+			want: `{"id":1,"name":"山田, 太郎","at":"2026-09-01T09:00:00+09:00","ratio":0.5}` + "\n" +
+				`{"id":2,"name":"佐藤","at":null,"ratio":0}` + "\n",
+		},
+		"yaml": {
+			format: outputYAML,
+			v:      views,
+			want: "- id: 1\n  name: 山田, 太郎\n  at: \"2026-09-01T09:00:00+09:00\"\n  ratio: 0.5\n" +
+				"- id: 2\n  name: 佐藤\n  at: null\n  ratio: 0\n",
+		},
+		"csv": {
+			format: outputCSV,
+			v:      views,
+			want:   "id,name,at,ratio\n1,\"山田, 太郎\",2026-09-01T09:00:00+09:00,0.5\n2,佐藤,,0\n",
+		},
+		"template": {
+			format:   outputJSON,
+			template: "{{.ID}}:{{.Name}}",
+			v:        views,
+			want:     "1:山田, 太郎\n2:佐藤\n",
+		},
+	}
+	for scenario, test := range tests {
+		var buf bytes.Buffer
+		r, err := newRenderer(&buf, test.format, test.template)
+		if !assert.NoError(t, err, scenario) {
+			continue
+		}
+		assert.NoError(t, r.render(test.v), scenario)
+		assert.Equal(t, test.want, buf.String(), scenario)
+	}
+}
+
+func TestNewRendererError(t *testing.T) {
+	_, err := newRenderer(nil, "xml", "")
+	assert.Error(t, err)
+	_, err = newRenderer(nil, outputTemplate, "")
+	assert.Error(t, err)
+	_, err = newRenderer(nil, "", "{{.ID")
+	assert.Error(t, err)
+}
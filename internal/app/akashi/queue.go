@@ -0,0 +1,382 @@
+package akashi
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"hapoon/go-akashi/pkg/akashi"
+
+	"github.com/spf13/cobra"
+)
+
+// queueFileName 打刻キューの既定のファイル名
+const queueFileName = "stamp-queue.json"
+
+// queueDedupeWindow 再送時に同じ打刻とみなすサーバの打刻日時の差
+// 応答を受け取れなかった打刻はサーバで処理された時刻で記録されているため幅をもたせる
+const queueDedupeWindow = 2 * time.Minute
+
+var (
+	// stamp queue discard
+	discardAll bool
+)
+
+func init() {
+	stampQueueDiscardCmd.Flags().BoolVar(&discardAll, "all", false, "Discard all pending stamps of the company")
+	stampQueueCmd.AddCommand(stampQueueListCmd)
+	stampQueueCmd.AddCommand(stampQueueReplayCmd)
+	stampQueueCmd.AddCommand(stampQueueDiscardCmd)
+	stampCmd.AddCommand(stampQueueCmd)
+}
+
+var stampQueueCmd = &cobra.Command{
+	Use:   "queue",
+	Short: "送信できなかった打刻のキュー",
+	Long: `送信できなかった打刻のキュー
+
+設定ファイルで有効にすると、通信の失敗で送信できなかった打刻を
+打刻した時刻・タイムゾーン・種別とともにキューに保存します。
+  stamp:
+    queue: true
+    queue_file: ~/.config/aka-cli/stamp-queue.json  # 省略時は設定ファイルと同じディレクトリ
+保存した打刻は replay で打刻日時の順に送信します。`,
+	Run: func(cmd *cobra.Command, args []string) {
+		// このコマンド単体では動作しないのでヘルプを表示する
+	},
+}
+
+var stampQueueListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "キューの打刻の一覧",
+	Long:  "キューに保存されている打刻を打刻日時の順に表示します。",
+	Args:  cobra.NoArgs,
+	Annotations: map[string]string{
+		annotationNoCredential: "true",
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		q := openStampQueue()
+		entries, err := q.load()
+		if err != nil {
+			log.Fatalln(err)
+		}
+		views := make([]queueView, 0, len(entries))
+		for _, e := range entries {
+			views = append(views, e.view(""))
+		}
+		if err := out.render(views); err != nil {
+			log.Fatalln(err)
+		}
+	},
+}
+
+var stampQueueReplayCmd = &cobra.Command{
+	Use:   "replay",
+	Short: "キューの打刻を送信する",
+	Long: `利用中の企業のキューの打刻を打刻日時の順に送信します。
+サーバに同じ種別の打刻が前後` + queueDedupeWindow.String() + `以内にある場合は送信せずにキューから削除します。
+送信に失敗した場合はそれ以降の打刻を送信せずにキューに残します。`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		q := openStampQueue()
+		results, err := q.replay(context.Background(), loginCompanyCode, accessToken)
+		if rerr := out.render(results); rerr != nil {
+			log.Fatalln(rerr)
+		}
+		if err != nil {
+			log.Fatalln(err)
+		}
+	},
+}
+
+var stampQueueDiscardCmd = &cobra.Command{
+	Use:   "discard [ID...]",
+	Short: "キューの打刻を削除する",
+	Long:  "指定したIDの打刻、または --all で利用中の企業のすべての打刻をキューから削除します。",
+	Annotations: map[string]string{
+		annotationNoCredential: "true",
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		if discardAll == (len(args) > 0) {
+			log.Fatalln("specify IDs or --all")
+		}
+		q := openStampQueue()
+		n, err := q.discard(loginCompanyCode, args, discardAll)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		fmt.Fprintf(os.Stderr, "discarded %d stamp(s)\n", n)
+	},
+}
+
+// queuedStamp キューに保存した打刻
+type queuedStamp struct {
+	ID               string           `json:"id"`
+	LoginCompanyCode string           `json:"login_company_code"`
+	Type             akashi.StampType `json:"type"`
+	StampedAt        time.Time        `json:"stamped_at"` // 打刻した時刻(タイムゾーンのオフセットを含む)
+	Timezone         string           `json:"timezone"`
+	QueuedAt         time.Time        `json:"queued_at"`
+	Error            string           `json:"error"` // 最後に送信に失敗した理由
+}
+
+// queueView キューの打刻の出力形式
+type queueView struct {
+	ID               string     `json:"id"`
+	LoginCompanyCode string     `json:"login_company_code"`
+	Type             int        `json:"type"`
+	TypeName         string     `json:"type_name"`
+	StampedAt        *time.Time `json:"stamped_at"`
+	Timezone         string     `json:"timezone"`
+	QueuedAt         *time.Time `json:"queued_at"`
+	Error            string     `json:"error"`
+	Result           string     `json:"result,omitempty"`
+}
+
+func (e queuedStamp) view(result string) queueView {
+	stampedAt, queuedAt := e.StampedAt, e.QueuedAt.In(akashi.Location)
+	return queueView{
+		ID:               e.ID,
+		LoginCompanyCode: e.LoginCompanyCode,
+		Type:             int(e.Type),
+		TypeName:         e.Type.String(),
+		StampedAt:        &stampedAt,
+		Timezone:         e.Timezone,
+		QueuedAt:         &queuedAt,
+		Error:            e.Error,
+		Result:           result,
+	}
+}
+
+// stampQueue 送信できなかった打刻を保存するファイル
+type stampQueue struct {
+	path string
+}
+
+// openStampQueue 設定ファイルで指定したキューを返す
+func openStampQueue() *stampQueue {
+	path := filepath.Join(filepath.Dir(configPath), queueFileName)
+	if cfg != nil && cfg.Stamp != nil && cfg.Stamp.QueueFile != "" {
+		path = cfg.Stamp.QueueFile
+	}
+	return &stampQueue{path: path}
+}
+
+// queueEnabled 通信の失敗時に打刻をキューに保存するか
+func queueEnabled() bool {
+	return cfg != nil && cfg.Stamp != nil && cfg.Stamp.Queue
+}
+
+// load キューの打刻を打刻日時の順に返す
+func (q *stampQueue) load() ([]queuedStamp, error) {
+	b, err := ioutil.ReadFile(q.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var entries []queuedStamp
+	if err := json.Unmarshal(b, &entries); err != nil {
+		return nil, fmt.Errorf("%s: %w", q.path, err)
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].StampedAt.Before(entries[j].StampedAt)
+	})
+	return entries, nil
+}
+
+func (q *stampQueue) save(entries []queuedStamp) error {
+	if entries == nil {
+		entries = []queuedStamp{}
+	}
+	b, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(q.path, b)
+}
+
+// add 打刻をキューに追加する
+func (q *stampQueue) add(e queuedStamp) (queuedStamp, error) {
+	entries, err := q.load()
+	if err != nil {
+		return e, err
+	}
+	if e.ID == "" {
+		id := make([]byte, 4)
+		if _, err := rand.Read(id); err != nil {
+			return e, err
+		}
+		e.ID = hex.EncodeToString(id)
+	}
+	return e, q.save(append(entries, e))
+}
+
+// discard 企業のキューの打刻を削除して削除した件数を返す
+func (q *stampQueue) discard(companyCode string, ids []string, all bool) (int, error) {
+	entries, err := q.load()
+	if err != nil {
+		return 0, err
+	}
+	remove := map[string]bool{}
+	for _, id := range ids {
+		remove[id] = true
+	}
+	var kept []queuedStamp
+	for _, e := range entries {
+		if all && e.LoginCompanyCode == companyCode || remove[e.ID] {
+			delete(remove, e.ID)
+			continue
+		}
+		kept = append(kept, e)
+	}
+	if len(remove) > 0 {
+		missing := make([]string, 0, len(remove))
+		for id := range remove {
+			missing = append(missing, strconv.Quote(id))
+		}
+		sort.Strings(missing)
+		return 0, fmt.Errorf("stamps not in the queue: %s", strings.Join(missing, ", "))
+	}
+	return len(entries) - len(kept), q.save(kept)
+}
+
+// replay 企業のキューの打刻を打刻日時の順に送信する
+//
+// サーバに同じ打刻がある場合は送信せずに削除する。失敗した打刻とそれ以降の打刻はキューに残す
+func (q *stampQueue) replay(ctx context.Context, companyCode, token string) ([]queueView, error) {
+	entries, err := q.load()
+	if err != nil {
+		return nil, err
+	}
+	var pending []queuedStamp
+	for _, e := range entries {
+		if e.LoginCompanyCode == companyCode {
+			pending = append(pending, e)
+		}
+	}
+	if len(pending) == 0 {
+		return []queueView{}, nil
+	}
+
+	// 以前に送信した打刻はサーバでは送信した時刻で記録されているので現在時刻まで取得する
+	r := dateRange{
+		Start: pending[0].StampedAt.Add(-queueDedupeWindow),
+		End:   pending[len(pending)-1].StampedAt.Add(queueDedupeWindow),
+	}
+	if now := time.Now(); now.After(r.End) {
+		r.End = now
+	}
+	existing, err := fetchStamps(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+
+	done := map[string]bool{}
+	results := make([]queueView, 0, len(pending))
+	var failure error
+	for i, e := range pending {
+		if failure != nil {
+			results = append(results, e.view("skipped"))
+			continue
+		}
+		if hasStamp(existing.Stamps, e.Type, e.StampedAt) {
+			done[e.ID] = true
+			e.Error = ""
+			results = append(results, e.view("duplicate"))
+			continue
+		}
+		at := e.StampedAt
+		_, err := akashi.PostStamp(ctx, akashi.PostStampParam{
+			LoginCompanyCode: companyCode,
+			Token:            token,
+			Type:             e.Type,
+			StampedAt:        akashi.NewAkTime(at),
+			Timezone:         e.Timezone,
+		})
+		if err != nil {
+			failure = fmt.Errorf("replay %s: %w", e.ID, err)
+			pending[i].Error = err.Error()
+			results = append(results, pending[i].view("failed"))
+			continue
+		}
+		done[e.ID] = true
+		e.Error = ""
+		results = append(results, e.view("posted"))
+	}
+
+	errs := map[string]string{}
+	for _, e := range pending {
+		errs[e.ID] = e.Error
+	}
+	var kept []queuedStamp
+	for _, e := range entries {
+		if done[e.ID] {
+			continue
+		}
+		if msg, ok := errs[e.ID]; ok {
+			e.Error = msg
+		}
+		kept = append(kept, e)
+	}
+	if err := q.save(kept); err != nil {
+		return results, err
+	}
+	return results, failure
+}
+
+// hasStamp atの前後queueDedupeWindow以内に打刻した同じ種別の打刻があるか
+// 打刻した時刻はローカル打刻時刻、ない場合はサーバでの打刻日時で比較する
+func hasStamp(stamps []akashi.Stamp, typ akashi.StampType, at time.Time) bool {
+	for _, s := range stamps {
+		t := s.ClientTime()
+		if s.Type != typ || t.IsZero() {
+			continue
+		}
+		d := t.Sub(at)
+		if d < 0 {
+			d = -d
+		}
+		if d <= queueDedupeWindow {
+			return true
+		}
+	}
+	return false
+}
+
+// enqueueStamp 送信できなかった打刻をキューに保存する
+// atがゼロ値の場合は現在時刻で打刻したものとする
+func enqueueStamp(typ akashi.StampType, at time.Time, cause error) {
+	now := time.Now()
+	if at.IsZero() {
+		at = now.In(akashi.Location).Truncate(time.Second)
+	}
+	e, err := openStampQueue().add(queuedStamp{
+		LoginCompanyCode: loginCompanyCode,
+		Type:             typ,
+		StampedAt:        at,
+		Timezone:         akashi.FormatTimezone(at),
+		QueuedAt:         now,
+		Error:            cause.Error(),
+	})
+	if err != nil {
+		log.Fatalln(cause, "and failed to queue the stamp:", err)
+	}
+	fmt.Fprintf(os.Stderr, "%v\nqueued %s at %s (id %s); run `aka-cli stamp queue replay` when the network is back\n",
+		cause, typ, at.Format(akashi.ReturnDateFormat), e.ID)
+}
+
+// errOffline 通信できないため状態を判定できない
+var errOffline = errors.New("cannot determine the current state while offline; use work-in, work-out, break-in or break-out")
@@ -0,0 +1,100 @@
+package akashi
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"hapoon/go-akashi/pkg/akashi"
+	"hapoon/go-akashi/pkg/akashi/akashitest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStampQueueReplay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "queue")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	srv := akashitest.NewServer()
+	defer srv.Close()
+	srv.AddToken("abc", "tok", 1, time.Time{})
+	at := func(hour, min int) time.Time { return time.Date(2026, 10, 16, hour, min, 0, 0, akashi.Location) }
+	replayedAt := time.Date(2026, 10, 17, 8, 0, 0, 0, akashi.Location)
+	srv.Now = func() time.Time { return replayedAt }
+	// 応答を受け取れなかったがサーバでは記録されていた出勤
+	srv.AddStamps("abc", 1, akashi.Stamp{Type: akashi.StampTypeGoToWork, StampedAt: akashi.NewAkTime(at(9, 1))})
+	// 以前の再送で記録されていた休憩戻(打刻日時は再送した時刻)
+	srv.AddStamps("abc", 1, akashi.Stamp{Type: akashi.StampTypeBreakReturn, StampedAt: akashi.NewAkTime(replayedAt.Add(-time.Hour)), LocalTime: akashi.NewAkTime(at(13, 0))})
+
+	defaultClient := akashi.DefaultClient
+	defer func() { akashi.DefaultClient = defaultClient }()
+	akashi.DefaultClient = srv.Client()
+	loginCompanyCode, accessToken = "abc", "tok"
+	defer func() { loginCompanyCode, accessToken = "", "" }()
+
+	q := &stampQueue{path: filepath.Join(dir, queueFileName)}
+	ny := time.FixedZone("EDT", -4*60*60)
+	for _, e := range []queuedStamp{
+		{ID: "out", LoginCompanyCode: "abc", Type: akashi.StampTypeLeaveWork, StampedAt: at(18, 0).In(ny), Timezone: "-04:00"},
+		{ID: "in", LoginCompanyCode: "abc", Type: akashi.StampTypeGoToWork, StampedAt: at(9, 0)},
+		{ID: "brk", LoginCompanyCode: "abc", Type: akashi.StampTypeBreak, StampedAt: at(12, 0)},
+		{ID: "ret", LoginCompanyCode: "abc", Type: akashi.StampTypeBreakReturn, StampedAt: at(13, 0)},
+		{ID: "other", LoginCompanyCode: "xyz", Type: akashi.StampTypeGoToWork, StampedAt: at(8, 0)},
+	} {
+		_, err := q.add(e)
+		assert.NoError(t, err)
+	}
+
+	results, err := q.replay(context.Background(), "abc", "tok")
+	assert.NoError(t, err)
+	var got []string
+	for _, r := range results {
+		got = append(got, r.ID+":"+r.Result)
+	}
+	assert.Equal(t, []string{"in:duplicate", "brk:posted", "ret:duplicate", "out:posted"}, got)
+
+	// 打刻日時はサーバで記録した時刻、打刻した時刻はローカル打刻時刻になる
+	stamps := srv.Stamps("abc", 1)
+	if assert.Len(t, stamps, 4) {
+		assert.Equal(t, akashi.StampTypeBreak, stamps[2].Type)
+		assert.True(t, replayedAt.Equal(stamps[2].StampedAt.Time), stamps[2].StampedAt)
+		assert.True(t, at(12, 0).Equal(stamps[2].LocalTime.Time), stamps[2].LocalTime)
+		assert.True(t, at(18, 0).Equal(stamps[3].LocalTime.Time), stamps[3].LocalTime)
+		assert.Equal(t, "-04:00", stamps[3].Timezone)
+	}
+	entries, err := q.load()
+	assert.NoError(t, err)
+	if assert.Len(t, entries, 1) {
+		assert.Equal(t, "other", entries[0].ID)
+	}
+
+	// 通信できない場合はキューに残す
+	srv.Close()
+	_, err = q.add(queuedStamp{ID: "late", LoginCompanyCode: "xyz", Type: akashi.StampTypeLeaveWork, StampedAt: at(17, 0)})
+	assert.NoError(t, err)
+	_, err = q.replay(context.Background(), "xyz", "tok")
+	assert.True(t, akashi.IsNetworkError(err), err)
+	entries, err = q.load()
+	assert.NoError(t, err)
+	assert.Len(t, entries, 2)
+
+	n, err := q.discard("xyz", []string{"late"}, false)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	_, err = q.discard("xyz", []string{"late"}, false)
+	assert.EqualError(t, err, `stamps not in the queue: "late"`)
+	// 存在しないIDをすべて報告し、何も削除しない
+	_, err = q.discard("xyz", []string{"zz", "late", "aa"}, true)
+	assert.EqualError(t, err, `stamps not in the queue: "aa", "late", "zz"`)
+	entries, _ = q.load()
+	assert.Len(t, entries, 1)
+	n, err = q.discard("xyz", nil, true)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+}
@@ -0,0 +1,237 @@
+package akashi
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"time"
+
+	"hapoon/go-akashi/pkg/akashi"
+	"hapoon/go-akashi/pkg/akashi/attendance"
+	"hapoon/go-akashi/pkg/akashi/calendar"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	// report timesheet
+	reportMonth   string
+	dayChangeHour int
+)
+
+func init() {
+	reportTimesheetCmd.Flags().StringVar(&reportMonth, "month", "this-month", "Month (YYYY-MM, this-month, last-month)")
+	reportTimesheetCmd.Flags().IntVar(&dayChangeHour, "day-change-hour", 0, "Stamps before this hour belong to the previous day")
+	reportCmd.AddCommand(reportTimesheetCmd)
+	rootCmd.AddCommand(reportCmd)
+}
+
+var reportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "Attendance reports",
+	Long:  "Attendance reports",
+	Run: func(cmd *cobra.Command, args []string) {
+		// このコマンド単体では動作しないのでヘルプを表示する
+	},
+}
+
+var reportTimesheetCmd = &cobra.Command{
+	Use:   "timesheet",
+	Short: "月次の勤務表",
+	Long: `1か月分の打刻から日ごとの勤務表を出力します。
+各行は日付・曜日・最初の出勤・最後の退勤・休憩(分)・実労働時間(時間)・備考で、
+最後にdateが"total"の行で月の合計を出力します。
+日付変更時刻以降の退勤は24時以降の時刻(例: 25:30)で表します。
+祝日と設定ファイルのcalendarで指定した休業日は備考に名称を出力します。
+
+  aka-cli report timesheet --month 2026-09 -o csv > 2026-09.csv`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if dayChangeHour < 0 || dayChangeHour > 23 {
+			log.Fatalln("--day-change-hour must be between 0 and 23")
+		}
+		month, err := parseDateExpr(reportMonth, time.Now(), akashi.Location)
+		if err != nil {
+			log.Fatalln("--month:", err)
+		}
+		if !month.isMonth() {
+			log.Fatalf("--month: %q is not a month", reportMonth)
+		}
+
+		cal, err := loadCalendar()
+		if err != nil {
+			log.Fatalln(err)
+		}
+
+		ctx := context.Background()
+		shift := time.Duration(dayChangeHour) * time.Hour
+		res, err := fetchStampsAt(ctx, dateRange{Start: month.Start.Add(shift), End: month.End.Add(shift)}, time.Now())
+		if err != nil {
+			log.Fatalln(err)
+		}
+		b := attendance.Builder{DayChangeHour: dayChangeHour, Location: akashi.Location}
+		if err := out.render(timesheet(month, b.BuildResponse(res), cal)); err != nil {
+			log.Fatalln(err)
+		}
+	},
+}
+
+// maxStampRange 1回のGetStampsで取得する期間
+const maxStampRange = 31 * 24 * time.Hour
+
+// fetchStamps 期間の打刻情報を取得する
+// 長い期間はmaxStampRangeごとに分けて取得する
+func fetchStamps(ctx context.Context, r dateRange) (akashi.GetStampResponse, error) {
+	var all akashi.GetStampResponse
+	for start := r.Start; !start.After(r.End); {
+		end := start.Add(maxStampRange - time.Second)
+		if end.After(r.End) {
+			end = r.End
+		}
+		p := akashi.GetStampParam{
+			LoginCompanyCode: loginCompanyCode,
+			Token:            accessToken,
+			StartDate:        start,
+			EndDate:          end,
+		}
+		res, err := akashi.GetStamps(ctx, p)
+		if err != nil {
+			return akashi.GetStampResponse{}, err
+		}
+		all.LoginCompanyCode = res.LoginCompanyCode
+		all.StaffID = res.StaffID
+		all.Stamps = append(all.Stamps, res.Stamps...)
+		start = end.Add(time.Second)
+	}
+	all.Count = len(all.Stamps)
+	return all, nil
+}
+
+// stampReceiptLag 打刻した時刻より後にサーバが受け付けた打刻(--atによる事後の打刻やキューの再送)を
+// 取得するために取得期間の終わりを延ばす時間
+const stampReceiptLag = 7 * 24 * time.Hour
+
+// fetchStampsAt 打刻した時刻(Stamp.ClientTime)がrに含まれる打刻情報を取得する
+// サーバは受け付けた日時で絞り込むので、取得期間の終わりをstampReceiptLagだけ(nowまで)延ばしてから絞り込む
+func fetchStampsAt(ctx context.Context, r dateRange, now time.Time) (akashi.GetStampResponse, error) {
+	q := r
+	if end := r.End.Add(stampReceiptLag); end.Before(now) {
+		q.End = end
+	} else if now.After(r.End) {
+		q.End = now
+	}
+	res, err := fetchStamps(ctx, q)
+	if err != nil {
+		return akashi.GetStampResponse{}, err
+	}
+	stamps := res.Stamps[:0]
+	for _, s := range res.Stamps {
+		// 打刻日時のない打刻は勤怠記録の不備として残す
+		if t := s.ClientTime(); t.IsZero() || (!t.Before(r.Start) && !t.After(r.End)) {
+			stamps = append(stamps, s)
+		}
+	}
+	res.Stamps, res.Count = stamps, len(stamps)
+	return res, nil
+}
+
+// timesheetRow 勤務表の出力形式
+type timesheetRow struct {
+	Date         string  `json:"date"`
+	Weekday      string  `json:"weekday"`
+	FirstIn      string  `json:"first_in"`
+	LastOut      string  `json:"last_out"`
+	BreakMinutes int     `json:"break_minutes"`
+	NetHours     float64 `json:"net_hours"`
+	Note         string  `json:"note"`
+}
+
+// weekdays 曜日の表記
+var weekdays = [...]string{"日", "月", "火", "水", "木", "金", "土"}
+
+// timesheet 月の各日の行と合計の行を作成する
+func timesheet(month dateRange, days []attendance.Day, cal *calendar.Calendar) []timesheetRow {
+	byDate := map[string]attendance.Day{}
+	for _, d := range days {
+		byDate[d.Date.Format("2006-01-02")] = d
+	}
+	var (
+		rows                   []timesheetRow
+		total                  time.Duration
+		breaks                 time.Duration
+		worked, incompleteDays int
+	)
+	for date := month.Start; !date.After(month.End); date = date.AddDate(0, 0, 1) {
+		key := date.Format("2006-01-02")
+		row := timesheetRow{Date: key, Weekday: weekdays[date.Weekday()]}
+		holiday, _ := cal.Holiday(date)
+		row.Note = holiday
+		if d, ok := byDate[key]; ok {
+			row.FirstIn = clock(date, d.FirstIn())
+			row.LastOut = clock(date, d.LastOut())
+			row.BreakMinutes = int(d.Break / time.Minute)
+			row.NetHours = hours(d.Net)
+			if n := note(d.Issues); n != "" {
+				row.Note = strings.Join(nonEmpty(holiday, n), "、")
+			}
+			total += d.Net
+			breaks += d.Break
+			if d.Net > 0 {
+				worked++
+			}
+			if len(d.Issues) > 0 {
+				incompleteDays++
+			}
+		}
+		rows = append(rows, row)
+	}
+	rows = append(rows, timesheetRow{
+		Date:         "total",
+		BreakMinutes: int(breaks / time.Minute),
+		NetHours:     hours(total),
+		Note:         fmt.Sprintf("出勤日数 %d日 / 不備 %d日", worked, incompleteDays),
+	})
+	return rows
+}
+
+// clock 勤務日の0時からの経過時刻をHH:MMで表す(翌日は24時以降)
+func clock(date, t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	t = t.In(date.Location())
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, date.Location())
+	h := t.Hour() + int(midnight.Sub(date).Hours()+0.5)
+	return fmt.Sprintf("%02d:%02d", h, t.Minute())
+}
+
+// hours 時間単位で小数第2位まで表す
+func hours(d time.Duration) float64 {
+	return math.Round(d.Hours()*100) / 100
+}
+
+// note 打刻の不備を備考として表す
+func note(issues []attendance.Issue) string {
+	var notes []string
+	seen := map[attendance.IssueKind]bool{}
+	for _, i := range issues {
+		if seen[i.Kind] {
+			continue
+		}
+		seen[i.Kind] = true
+		notes = append(notes, i.Kind.String())
+	}
+	return strings.Join(notes, "、")
+}
+
+func nonEmpty(ss ...string) []string {
+	var r []string
+	for _, s := range ss {
+		if s != "" {
+			r = append(r, s)
+		}
+	}
+	return r
+}
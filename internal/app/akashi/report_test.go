@@ -0,0 +1,90 @@
+package akashi
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"hapoon/go-akashi/pkg/akashi"
+	"hapoon/go-akashi/pkg/akashi/akashitest"
+	"hapoon/go-akashi/pkg/akashi/attendance"
+	"hapoon/go-akashi/pkg/akashi/calendar"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimesheet(t *testing.T) {
+	jst := time.FixedZone("JST", 9*60*60)
+	at := func(day, hour, min int) *akashi.AkTime {
+		return akashi.NewAkTime(time.Date(2026, 9, day, hour, min, 0, 0, jst))
+	}
+	stamps := []akashi.Stamp{
+		{Type: akashi.StampTypeGoToWork, StampedAt: at(1, 9, 0)},
+		{Type: akashi.StampTypeBreak, StampedAt: at(1, 12, 0)},
+		{Type: akashi.StampTypeBreakReturn, StampedAt: at(1, 12, 45)},
+		{Type: akashi.StampTypeLeaveWork, StampedAt: at(1, 18, 0)},
+		{Type: akashi.StampTypeGoToWork, StampedAt: at(2, 20, 0)},
+		{Type: akashi.StampTypeLeaveWork, StampedAt: at(3, 1, 30)},
+		{Type: akashi.StampTypeGoToWork, StampedAt: at(4, 9, 0)},
+		{Type: akashi.StampTypeGoToWork, StampedAt: at(22, 10, 0)},
+	}
+	b := attendance.Builder{DayChangeHour: 5, Location: jst}
+	month := months(2026, time.September, jst)
+	cal := calendar.New()
+	cal.AddClosure(time.Date(2026, 9, 30, 0, 0, 0, 0, jst), "棚卸")
+	rows := timesheet(month, b.Build(1, stamps), cal)
+
+	if !assert.Len(t, rows, 31) {
+		return
+	}
+	assert.Equal(t, timesheetRow{Date: "2026-09-01", Weekday: "火", FirstIn: "09:00", LastOut: "18:00", BreakMinutes: 45, NetHours: 8.25}, rows[0])
+	assert.Equal(t, timesheetRow{Date: "2026-09-02", Weekday: "水", FirstIn: "20:00", LastOut: "25:30", NetHours: 5.5}, rows[1])
+	assert.Equal(t, timesheetRow{Date: "2026-09-03", Weekday: "木"}, rows[2])
+	assert.Equal(t, timesheetRow{Date: "2026-09-04", Weekday: "金", FirstIn: "09:00", Note: "退勤打刻なし"}, rows[3])
+	assert.Equal(t, timesheetRow{Date: "2026-09-21", Weekday: "月", Note: "敬老の日"}, rows[20])
+	assert.Equal(t, timesheetRow{Date: "2026-09-22", Weekday: "火", FirstIn: "10:00", Note: "国民の休日、退勤打刻なし"}, rows[21])
+	assert.Equal(t, timesheetRow{Date: "2026-09-30", Weekday: "水", Note: "棚卸"}, rows[29])
+	assert.Equal(t, timesheetRow{Date: "total", BreakMinutes: 45, NetHours: 13.75, Note: "出勤日数 2日 / 不備 2日"}, rows[30])
+}
+
+func TestFetchStampsAtBackdated(t *testing.T) {
+	srv := akashitest.NewServer()
+	defer srv.Close()
+	srv.AddToken("abc", "tok", 1, time.Time{})
+	at := func(day, hour, min int) time.Time { return time.Date(2026, 10, day, hour, min, 0, 0, akashi.Location) }
+	srv.AddStamps("abc", 1, akashi.Stamp{Type: akashi.StampTypeGoToWork, StampedAt: akashi.NewAkTime(at(15, 9, 0))})
+	now := at(16, 9, 0)
+	srv.Now = func() time.Time { return now }
+
+	defaultClient := akashi.DefaultClient
+	defer func() { akashi.DefaultClient = defaultClient }()
+	akashi.DefaultClient = srv.Client()
+	loginCompanyCode, accessToken = "abc", "tok"
+	defer func() { loginCompanyCode, accessToken = "", "" }()
+	ctx := context.Background()
+
+	// 前日18:30の退勤を翌朝に打刻する(サーバは受け付けた9:00を打刻日時にする)
+	_, err := akashi.PostStamp(ctx, akashi.PostStampParam{
+		LoginCompanyCode: "abc",
+		Token:            "tok",
+		Type:             akashi.StampTypeLeaveWork,
+		StampedAt:        akashi.NewAkTime(at(15, 18, 30)),
+	})
+	assert.NoError(t, err)
+
+	// 打刻した日に数える
+	res, err := fetchStampsAt(ctx, dateRange{Start: at(15, 0, 0), End: at(16, 0, 0).Add(-time.Second)}, now)
+	assert.NoError(t, err)
+	days := attendance.Builder{Location: akashi.Location}.BuildResponse(res)
+	if assert.Len(t, days, 1) {
+		assert.True(t, days[0].Date.Equal(at(15, 0, 0)))
+		assert.Equal(t, attendance.StateFinished, days[0].State())
+		assert.Equal(t, 9*time.Hour+30*time.Minute, days[0].Net)
+	}
+
+	// 受け付けた日の勤務状態には含めない
+	day, err := today(ctx, now)
+	assert.NoError(t, err)
+	assert.Empty(t, day.Stamps)
+	assert.Equal(t, attendance.StateNotStarted, day.State())
+}
@@ -1,32 +1,141 @@
 package akashi
 
 import (
+	"errors"
 	"fmt"
+	"log"
 	"os"
+	"strings"
+	"time"
+
+	"hapoon/go-akashi/pkg/akashi"
 
 	"github.com/spf13/cobra"
 )
 
 var (
-	verbose bool
+	verbose      bool
+	configPath   string
+	profileName  string
+	timezoneName string
+	baseURL      string
+
+	// cfg 読み込んだ設定ファイル
+	cfg *Config
+	// active 決定した設定値
+	active settings
+	// flagSettings コマンドラインフラグで指定された設定値
+	flagSettings settings
+	// credentials アクセストークンの保存先
+	credentials CredentialStore
+	// clientOptions DefaultClientの生成に利用したオプション
+	clientOptions []akashi.Option
 )
 
+// annotationNoCredential 保存したアクセストークンを読み込まないコマンドに付ける
+const annotationNoCredential = "no-credential"
+
 func init() {
 	rootCmd.PersistentFlags().StringVar(&loginCompanyCode, "company-code", "", "Login company code")
 	rootCmd.PersistentFlags().StringVarP(&accessToken, "token", "t", "", "Access token")
 	rootCmd.PersistentFlags().BoolVar(&verbose, "verbose", false, "verbose output")
+	rootCmd.PersistentFlags().StringVar(&configPath, "config", "", "Config file (default $XDG_CONFIG_HOME/aka-cli/config.yaml)")
+	rootCmd.PersistentFlags().StringVarP(&profileName, "profile", "p", "", "Profile name in the config file")
+	rootCmd.PersistentFlags().StringVar(&timezoneName, "akashi-timezone", "", "Timezone of AKASHI date and time (default Asia/Tokyo)")
+	rootCmd.PersistentFlags().StringVar(&baseURL, "base-url", "", "Base URL of AKASHI API")
+	rootCmd.PersistentFlags().StringVarP(&outputFormat, "output", "o", "", "Output format ("+strings.Join(outputFormats, ", ")+") (default table)")
+	rootCmd.PersistentFlags().StringVar(&outputTemplateText, "template", "", "Go template applied to each result (implies --output template)")
 }
 
 var rootCmd = &cobra.Command{
 	Use:   "aka-cli",
 	Short: "aka-cli is a command line tool for AKASHI",
 	Long: `A command line tool for AKASHI
-			Complete documentation is available at ...`,
+			Complete documentation is available at ...
+
+Settings are resolved in the following order:
+  1. command line flags (--company-code, --token, --akashi-timezone, ...)
+  2. environment variables (AKASHI_COMPANY_CODE, AKASHI_TOKEN, AKASHI_TIMEZONE, AKASHI_OUTPUT, AKASHI_BASE_URL)
+  3. the selected profile (--profile > AKASHI_PROFILE > current profile > "default")`,
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		return setup(cmd.Annotations[annotationNoCredential] == "")
+	},
 	Run: func(cmd *cobra.Command, args []string) {
 		// Do Stuff Here
 	},
 }
 
+// setup 設定ファイルを読み込んでコマンドが利用する設定とクライアントを準備する
+// withCredentialがtrueの場合、フラグや環境変数でアクセストークンが指定されていなければ保存先から読み込む
+func setup(withCredential bool) error {
+	if configPath == "" {
+		p, err := defaultConfigPath()
+		if err != nil {
+			return err
+		}
+		configPath = p
+	}
+	c, err := loadConfig(configPath)
+	if err != nil {
+		return err
+	}
+	cfg = c
+	store, err := newCredentialStore(cfg, configPath, cfg.CredentialStore, os.Getenv)
+	if err != nil {
+		return err
+	}
+	credentials = store
+
+	flagSettings = settings{
+		Profile:     profileName,
+		CompanyCode: loginCompanyCode,
+		Token:       accessToken,
+		Timezone:    timezoneName,
+		Output:      outputFormat,
+		BaseURL:     baseURL,
+	}
+	s, err := resolveSettings(cfg, flagSettings, os.Getenv)
+	active = s
+	if err != nil {
+		return err
+	}
+	r, err := newRenderer(os.Stdout, s.Output, outputTemplateText)
+	if err != nil {
+		return err
+	}
+	out = r
+	if withCredential && s.Token == "" {
+		c, err := credentials.Get(s.Profile)
+		if err != nil && !errors.Is(err, errCredentialNotFound) {
+			return err
+		}
+		s.Token = c.Token
+		active = s
+	}
+	loginCompanyCode = s.CompanyCode
+	accessToken = s.Token
+
+	if s.Timezone != "" {
+		loc, err := time.LoadLocation(s.Timezone)
+		if err != nil {
+			return fmt.Errorf("invalid timezone %q: %w", s.Timezone, err)
+		}
+		akashi.Location = loc
+	}
+
+	var opts []akashi.Option
+	if s.BaseURL != "" {
+		opts = append(opts, akashi.WithBaseURL(s.BaseURL))
+	}
+	if verbose {
+		logger := akashi.NewStdLogger(log.New(os.Stderr, "", log.LstdFlags), akashi.LevelDebug)
+		opts = append(opts, akashi.WithLogger(logger))
+	}
+	clientOptions = opts
+	akashi.DefaultClient = akashi.NewClient(opts...)
+	return nil
+}
+
 // Execute is execution root command
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
@@ -0,0 +1,241 @@
+package akashi
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	// defaultProfile プロファイルの指定がない場合に利用するプロファイル名
+	defaultProfile = "default"
+	// configDirName 設定ディレクトリ名
+	configDirName = "aka-cli"
+	// configFileName 設定ファイル名
+	configFileName = "config.yaml"
+)
+
+// 設定を上書きする環境変数
+const (
+	envConfig      = "AKASHI_CONFIG"
+	envProfile     = "AKASHI_PROFILE"
+	envCompanyCode = "AKASHI_COMPANY_CODE"
+	envToken       = "AKASHI_TOKEN"
+	envTimezone    = "AKASHI_TIMEZONE"
+	envOutput      = "AKASHI_OUTPUT"
+	envBaseURL     = "AKASHI_BASE_URL"
+	envPassphrase  = "AKASHI_PASSPHRASE"
+	envCredKey     = "AKASHI_CREDENTIAL_KEY"
+)
+
+// Config 設定ファイル
+type Config struct {
+	CurrentProfile  string              `yaml:"current_profile,omitempty"`  // 利用中のプロファイル名
+	Profiles        map[string]*Profile `yaml:"profiles,omitempty"`         // プロファイル
+	CredentialStore string              `yaml:"credential_store,omitempty"` // アクセストークンの保存先(plain, encrypted)
+	CredentialFile  string              `yaml:"credential_file,omitempty"`  // encryptedの保存先ファイル(未指定時は設定ファイルと同じディレクトリ)
+	Compliance      *ComplianceConfig   `yaml:"compliance,omitempty"`       // compliance checkの基準
+	Calendar        string              `yaml:"calendar,omitempty"`         // 会社カレンダーのファイル(YAML, ICS)
+	Stamp           *StampConfig        `yaml:"stamp,omitempty"`            // 打刻の設定
+	Daemon          *DaemonConfig       `yaml:"daemon,omitempty"`           // daemonの打刻スケジュール
+}
+
+// DaemonConfig daemonの打刻スケジュール
+// 時刻はHH:MM形式で、会社カレンダーの営業日に打刻する
+type DaemonConfig struct {
+	WorkIn     string `yaml:"work_in,omitempty"`     // 出勤の時刻(例: 09:00)
+	WorkOut    string `yaml:"work_out,omitempty"`    // 退勤の時刻(例: 18:00)
+	BreakStart string `yaml:"break_start,omitempty"` // 昼休憩の開始時刻(例: 12:00)
+	BreakEnd   string `yaml:"break_end,omitempty"`   // 昼休憩の終了時刻(例: 13:00)
+	AuditLog   string `yaml:"audit_log,omitempty"`   // 監査ログのファイル(未指定時は設定ファイルと同じディレクトリ)
+}
+
+// StampConfig 打刻の設定
+type StampConfig struct {
+	MaxPast   string `yaml:"max_past,omitempty"`   // --at で指定できる過去の範囲(例: 48h)
+	MaxFuture string `yaml:"max_future,omitempty"` // --at で指定できる未来の範囲。端末の時計のずれの許容範囲(例: 5m)
+	Queue     bool   `yaml:"queue,omitempty"`      // 通信の失敗で送信できなかった打刻をキューに保存する
+	QueueFile string `yaml:"queue_file,omitempty"` // キューのファイル(未指定時は設定ファイルと同じディレクトリ)
+	// DedupeWindow 同じ種別の打刻がこの時間内にある場合は打刻しない(例: 1m)
+	DedupeWindow string `yaml:"dedupe_window,omitempty"`
+}
+
+// ComplianceConfig compliance checkの基準
+// 未指定の項目は労働基準法と36協定の原則の基準を利用する
+type ComplianceConfig struct {
+	DailyLimit         string   `yaml:"daily_limit,omitempty"`          // 1日の法定労働時間(例: 8h)
+	WeeklyLimit        string   `yaml:"weekly_limit,omitempty"`         // 1週の法定労働時間(例: 40h)
+	WeekStart          string   `yaml:"week_start,omitempty"`           // 週の起算日(例: sunday)
+	MonthlyOvertimeCap string   `yaml:"monthly_overtime_cap,omitempty"` // 36協定の1か月の上限(例: 45h)
+	YearlyOvertimeCap  string   `yaml:"yearly_overtime_cap,omitempty"`  // 36協定の1年の上限(例: 360h)
+	YearStartMonth     int      `yaml:"year_start_month,omitempty"`     // 36協定の対象期間の起算月
+	Interval           string   `yaml:"interval,omitempty"`             // 勤務間インターバル(例: 11h)
+	Disabled           []string `yaml:"disabled,omitempty"`             // 検査しないルール名
+}
+
+// Profile 接続先ごとの設定
+type Profile struct {
+	CompanyCode string `yaml:"company_code,omitempty"` // AKASHI企業ID
+	Token       string `yaml:"token,omitempty"`        // アクセストークン(credential_storeがplainの場合)
+	Timezone    string `yaml:"timezone,omitempty"`     // タイムゾーン
+	Output      string `yaml:"output,omitempty"`       // デフォルトの出力形式
+	BaseURL     string `yaml:"base_url,omitempty"`     // APIのベースURL
+
+	StaffID        int        `yaml:"staff_id,omitempty"`         // アクセストークンの従業員ID
+	TokenExpiredAt *time.Time `yaml:"token_expired_at,omitempty"` // アクセストークンの有効期限(credential_storeがplainの場合)
+}
+
+// defaultConfigPath 設定ファイルのパス
+// AKASHI_CONFIGが指定されていない場合はXDG_CONFIG_HOME(未指定時は~/.config)以下
+func defaultConfigPath() (string, error) {
+	if p := os.Getenv(envConfig); p != "" {
+		return p, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, configDirName, configFileName), nil
+}
+
+// loadConfig 設定ファイルを読み込む
+// ファイルが存在しない場合は空の設定を返す
+func loadConfig(path string) (*Config, error) {
+	cfg := &Config{Profiles: map[string]*Profile{}}
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(b, cfg); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if cfg.Profiles == nil {
+		cfg.Profiles = map[string]*Profile{}
+	}
+	return cfg, nil
+}
+
+// save 設定ファイルを書き込む
+// 途中で失敗しても既存のファイルが壊れないように一時ファイルを経由する
+func (c *Config) save(path string) error {
+	b, err := yaml.Marshal(c)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, b)
+}
+
+// writeFileAtomic 所有者のみ読み書きできるファイルとしてbを書き込む
+func writeFileAtomic(path string, b []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	f, err := ioutil.TempFile(dir, "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	defer os.Remove(tmp)
+	if err := f.Chmod(0600); err != nil {
+		f.Close()
+		return err
+	}
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// activeProfile 利用中のプロファイルを返す
+// 存在しない場合は作成する
+func (c *Config) activeProfile(name string) *Profile {
+	p, ok := c.Profiles[name]
+	if !ok {
+		p = &Profile{}
+		c.Profiles[name] = p
+		if c.CurrentProfile == "" {
+			c.CurrentProfile = name
+		}
+	}
+	return p
+}
+
+// profileNames プロファイル名を昇順で返す
+func (c *Config) profileNames() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for k := range c.Profiles {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// settings コマンドが利用する設定値
+type settings struct {
+	Profile     string
+	CompanyCode string
+	Token       string
+	Timezone    string
+	Output      string
+	BaseURL     string
+}
+
+// errProfileNotFound 指定したプロファイルが存在しない
+var errProfileNotFound = errors.New("profile not found")
+
+// resolveSettings 設定値を決定する
+//
+// 優先順位は コマンドラインフラグ > 環境変数 > プロファイル。
+// プロファイルは --profile > AKASHI_PROFILE > current_profile > default の順に選択する。
+// 明示的に指定したプロファイルが存在しない場合はプロファイル以外から決定した設定値とerrProfileNotFoundを返す
+func resolveSettings(cfg *Config, flags settings, getenv func(string) string) (settings, error) {
+	s := settings{
+		Profile: first(flags.Profile, getenv(envProfile)),
+	}
+	explicit := s.Profile != ""
+	if !explicit {
+		s.Profile = first(cfg.CurrentProfile, defaultProfile)
+	}
+	var err error
+	p, ok := cfg.Profiles[s.Profile]
+	if !ok {
+		if explicit {
+			err = fmt.Errorf("%w: %s", errProfileNotFound, s.Profile)
+		}
+		p = &Profile{}
+	}
+	s.CompanyCode = first(flags.CompanyCode, getenv(envCompanyCode), p.CompanyCode)
+	s.Token = first(flags.Token, getenv(envToken), p.Token)
+	s.Timezone = first(flags.Timezone, getenv(envTimezone), p.Timezone)
+	s.Output = first(flags.Output, getenv(envOutput), p.Output)
+	s.BaseURL = first(flags.BaseURL, getenv(envBaseURL), p.BaseURL)
+	return s, err
+}
+
+// first 最初の空でない値を返す
+func first(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,106 @@
+package akashi
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveSettings(t *testing.T) {
+	cfg := &Config{
+		CurrentProfile: "work",
+		Profiles: map[string]*Profile{
+			"default": {CompanyCode: "def", Token: "def-token"},
+			"work":    {CompanyCode: "work", Token: "work-token", Timezone: "Asia/Tokyo", Output: "table"},
+			"home":    {CompanyCode: "home", Token: "home-token", BaseURL: "http://localhost"},
+		},
+	}
+	tests := map[string]struct {
+		cfg   *Config
+		flags settings
+		env   map[string]string
+		want  settings
+		err   error
+	}{
+		"current profile": {
+			cfg:  cfg,
+			want: settings{Profile: "work", CompanyCode: "work", Token: "work-token", Timezone: "Asia/Tokyo", Output: "table"},
+		},
+		"default profile": {
+			cfg:  &Config{Profiles: cfg.Profiles},
+			want: settings{Profile: "default", CompanyCode: "def", Token: "def-token"},
+		},
+		"no config": {
+			cfg:  &Config{},
+			want: settings{Profile: "default"},
+		},
+		"profile from env": {
+			cfg:  cfg,
+			env:  map[string]string{envProfile: "home"},
+			want: settings{Profile: "home", CompanyCode: "home", Token: "home-token", BaseURL: "http://localhost"},
+		},
+		"profile flag beats env": {
+			cfg:   cfg,
+			flags: settings{Profile: "default"},
+			env:   map[string]string{envProfile: "home"},
+			want:  settings{Profile: "default", CompanyCode: "def", Token: "def-token"},
+		},
+		"env beats profile": {
+			cfg:  cfg,
+			env:  map[string]string{envToken: "env-token", envOutput: "json"},
+			want: settings{Profile: "work", CompanyCode: "work", Token: "env-token", Timezone: "Asia/Tokyo", Output: "json"},
+		},
+		"flag beats env": {
+			cfg:   cfg,
+			flags: settings{Token: "flag-token", Timezone: "UTC"},
+			env:   map[string]string{envToken: "env-token", envTimezone: "Asia/Seoul"},
+			want:  settings{Profile: "work", CompanyCode: "work", Token: "flag-token", Timezone: "UTC", Output: "table"},
+		},
+		"missing profile": {
+			cfg:   cfg,
+			flags: settings{Profile: "nope", CompanyCode: "abc"},
+			want:  settings{Profile: "nope", CompanyCode: "abc"},
+			err:   errProfileNotFound,
+		},
+	}
+	for scenario, test := range tests {
+		getenv := func(k string) string { return test.env[k] }
+		s, err := resolveSettings(test.cfg, test.flags, getenv)
+		if test.err != nil {
+			assert.True(t, errors.Is(err, test.err), scenario)
+		} else {
+			assert.NoError(t, err, scenario)
+		}
+		assert.Equal(t, test.want, s, scenario)
+	}
+}
+
+func TestConfigSaveLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "aka-cli")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "aka-cli", "config.yaml")
+
+	cfg, err := loadConfig(path)
+	assert.NoError(t, err)
+	assert.Empty(t, cfg.Profiles)
+
+	cfg.CurrentProfile = "work"
+	cfg.Profiles["work"] = &Profile{CompanyCode: "abc", Token: "secret"}
+	assert.NoError(t, cfg.save(path))
+
+	fi, err := os.Stat(path)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), fi.Mode().Perm())
+
+	loaded, err := loadConfig(path)
+	assert.NoError(t, err)
+	assert.Equal(t, cfg, loaded)
+	assert.Equal(t, []string{"work"}, loaded.profileNames())
+}
@@ -2,7 +2,6 @@ package akashi
 
 import (
 	"context"
-	"fmt"
 	"log"
 	"os"
 
@@ -44,7 +43,47 @@ var staffCmd = &cobra.Command{
 			log.Fatalln(err)
 			os.Exit(1)
 		}
-		fmt.Println("response:")
-		fmt.Printf("%+v\n", res)
+		views := make([]staffView, 0, len(res.Staffs))
+		for _, v := range res.Staffs {
+			views = append(views, newStaffView(v))
+		}
+		if err := out.render(views); err != nil {
+			log.Fatalln(err)
+		}
 	},
 }
+
+// staffView 従業員情報の出力形式
+type staffView struct {
+	StaffID                int    `json:"staff_id"`
+	LastName               string `json:"last_name"`
+	FirstName              string `json:"first_name"`
+	LastNameKana           string `json:"last_name_kana"`
+	FirstNameKana          string `json:"first_name_kana"`
+	StaffNum               string `json:"staff_num"`
+	OrganizationID         int    `json:"organization_id"`
+	OrganizationName       string `json:"organization_name"`
+	EmploymentCategoryID   int    `json:"employment_category_id"`
+	EmploymentCategoryName string `json:"employment_category_name"`
+	PermissionGroupName    string `json:"permission_group_name"`
+	Tag                    string `json:"tag"`
+	Remarks                string `json:"remarks"`
+}
+
+func newStaffView(s akashi.Staff) staffView {
+	return staffView{
+		StaffID:                s.ID,
+		LastName:               s.LastName,
+		FirstName:              s.FirstName,
+		LastNameKana:           s.LastNameKana,
+		FirstNameKana:          s.FirstNameKana,
+		StaffNum:               s.StaffNum,
+		OrganizationID:         s.Organization.ID,
+		OrganizationName:       s.Organization.Name,
+		EmploymentCategoryID:   s.EmploymentCategory.ID,
+		EmploymentCategoryName: s.EmploymentCategory.Name,
+		PermissionGroupName:    s.PermissionGroup.Name,
+		Tag:                    s.Tag,
+		Remarks:                s.Remarks,
+	}
+}
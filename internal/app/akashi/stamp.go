@@ -1,13 +1,17 @@
 package akashi
 
 import (
+	"bufio"
 	"context"
 	"fmt"
+	"io"
 	"log"
 	"os"
+	"strings"
 	"time"
 
 	"hapoon/go-akashi/pkg/akashi"
+	"hapoon/go-akashi/pkg/akashi/attendance"
 
 	"github.com/spf13/cobra"
 )
@@ -17,17 +21,27 @@ var (
 	startDate string
 	endDate   string
 	// PostStamp
-	stampType int
-	stampedAt string
-	timezone  string
+	stampType     int
+	stampedAt     string
+	stampTimezone string
+	assumeYes     bool
+	forceStamp    bool
 )
 
 func init() {
 	// 打刻情報取得
-	stampGetCmd.Flags().StringVarP(&startDate, "start-date", "s", "", "Start date")
+	stampGetCmd.Flags().StringVarP(&startDate, "start-date", "s", "", "Start date (default today)")
 	stampGetCmd.Flags().StringVarP(&endDate, "end-date", "e", "", "End date")
 	stampCmd.AddCommand(stampGetCmd)
 	// 打刻
+	stampTouchCmd.Flags().BoolVarP(&assumeYes, "yes", "y", false, "Stamp without confirmation")
+	for _, c := range []*cobra.Command{stampGoToWorkCmd, stampLeaveWorkCmd, stampBreakCmd, stampBreakReturnCmd} {
+		c.Flags().BoolVar(&forceStamp, "force", false, "Stamp even if it does not fit the current state")
+	}
+	for _, c := range []*cobra.Command{stampTouchCmd, stampGoToWorkCmd, stampLeaveWorkCmd, stampBreakCmd, stampBreakReturnCmd} {
+		c.Flags().StringVar(&stampedAt, "at", "", "Stamp time ("+timeExprHelp+")")
+		c.Flags().StringVar(&stampTimezone, "timezone", "", "Timezone where the stamp is made (IANA name or ±HH:MM, default the AKASHI timezone)")
+	}
 	stampCmd.AddCommand(stampTouchCmd)
 	stampCmd.AddCommand(stampGoToWorkCmd)
 	stampCmd.AddCommand(stampLeaveWorkCmd)
@@ -46,159 +60,373 @@ var stampCmd = &cobra.Command{
 }
 
 var stampGetCmd = &cobra.Command{
-	Use:   "get",
+	Use:   "get [DATE]",
 	Short: "打刻情報の取得",
-	Long:  `打刻情報の取得`,
+	Long: `打刻情報の取得
+
+期間は --start-date と --end-date、または DATE で指定します。指定できる形式:
+  ` + dateExprHelp + `
+月や週は期間全体を表し、日付は設定されたタイムゾーンで解釈します。
+指定がない場合は今日、片方だけの場合はその形式が表す期間を取得します。
+
+  aka-cli stamp get last-month
+  aka-cli stamp get -s 2026-09-01 -e 2026/09/15`,
+	Args: cobra.MaximumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		if verbose {
 			log.Println("args:", args)
 		}
 		ctx := context.Background()
-		ts, err := time.Parse(akashi.DateFormat, startDate)
-		if err != nil {
-			log.Fatalln(err)
-			os.Exit(1)
+		start := startDate
+		if len(args) > 0 {
+			if startDate != "" || endDate != "" {
+				log.Fatalln("DATE cannot be used with --start-date or --end-date")
+			}
+			start = args[0]
 		}
-		te, err := time.Parse(akashi.DateFormat, endDate)
+		r, err := resolveDateRange(start, endDate, time.Now(), akashi.Location)
 		if err != nil {
 			log.Fatalln(err)
-			os.Exit(1)
 		}
 		p := akashi.GetStampParam{
 			LoginCompanyCode: loginCompanyCode,
 			Token:            accessToken,
-			StartDate:        ts,
-			EndDate:          te,
+			StartDate:        r.Start,
+			EndDate:          r.End,
 		}
 		res, err := akashi.GetStamps(ctx, p)
 		if err != nil {
 			log.Fatalln(err)
 			os.Exit(1)
 		}
-		fmt.Println("企業ID:", res.LoginCompanyCode)
-		fmt.Println("従業員ID:", res.StaffID)
-		fmt.Println("件数:", res.Count)
+		views := make([]stampView, 0, len(res.Stamps))
 		for _, v := range res.Stamps {
-			fmt.Println("------------------------------------")
-			fmt.Println("打刻日時:", v.StampedAt)
-			fmt.Println("打刻種別:", v.Type)
-			fmt.Println("ローカル打刻時刻:", v.LocalTime)
-			fmt.Println("タイムゾーン:", v.Timezone)
-			fmt.Println("打刻方法:", v.Attributes.Method)
-			fmt.Println("組織ID:", v.Attributes.OrgID)
-			fmt.Println("勤務地ID:", v.Attributes.WorkplaceID)
-			fmt.Println("緯度:", v.Attributes.Latitude)
-			fmt.Println("経度:", v.Attributes.Longitude)
-			fmt.Println("IPアドレス:", v.Attributes.IP)
+			views = append(views, stampView{
+				StaffID:     res.StaffID,
+				StampedAt:   akTime(v.StampedAt),
+				Type:        int(v.Type),
+				TypeName:    v.Type.String(),
+				LocalTime:   akTime(v.LocalTime),
+				Timezone:    v.Timezone,
+				Method:      v.Attributes.Method,
+				OrgID:       v.Attributes.OrgID,
+				WorkplaceID: v.Attributes.WorkplaceID,
+				Latitude:    v.Attributes.Latitude,
+				Longitude:   v.Attributes.Longitude,
+				IP:          v.Attributes.IP,
+			})
+		}
+		if err := out.render(views); err != nil {
+			log.Fatalln(err)
 		}
 	},
 }
 
+// stampView 打刻情報の出力形式
+type stampView struct {
+	StaffID     int        `json:"staff_id"`
+	StampedAt   *time.Time `json:"stamped_at"`
+	Type        int        `json:"type"`
+	TypeName    string     `json:"type_name"`
+	LocalTime   *time.Time `json:"local_time"`
+	Timezone    string     `json:"timezone"`
+	Method      int        `json:"method"`
+	OrgID       int        `json:"org_id"`
+	WorkplaceID int        `json:"workplace_id"`
+	Latitude    float32    `json:"latitude"`
+	Longitude   float32    `json:"longitude"`
+	IP          string     `json:"ip"`
+}
+
 var stampTouchCmd = &cobra.Command{
 	Use:   "touch",
 	Short: "打刻",
 	Long: `打刻
-	状況に合わせた打刻を行います。
-	未出勤時は出勤の打刻を、出勤時は退勤の打刻を、休憩中は休憩戻りの打刻を行います。
-	`,
+	今日の打刻から現在の状態を判定し、状況に合わせた打刻を行います。
+	未出勤・退勤済の時は出勤の打刻を、勤務中は退勤の打刻を、休憩中は休憩戻りの打刻を行います。
+	打刻の種別を表示して確認するので、確認せずに打刻する場合は --yes を指定します。
+	` + stampTimeHelp,
+	Args: cobra.NoArgs,
 	Run: func(cmd *cobra.Command, args []string) {
 		ctx := context.Background()
-		p := akashi.PostStampParam{
-			LoginCompanyCode: loginCompanyCode,
-			Token:            accessToken,
+		at, err := stampTime(time.Now())
+		if err != nil {
+			log.Fatalln(err)
 		}
-		res, err := akashi.PostStamp(ctx, p)
+		day, err := today(ctx, at)
 		if err != nil {
+			if queueEnabled() && akashi.IsNetworkError(err) {
+				log.Fatalf("%v\n%v", err, errOffline)
+			}
 			log.Fatalln(err)
-			os.Exit(1)
 		}
-		printStampPostResponse(res)
+		state := day.State()
+		typ := state.Next()
+		fmt.Fprintf(os.Stderr, "現在の状態: %s\n打刻の種別: %s\n", state, typ)
+		if !assumeYes {
+			ok, err := confirm(os.Stdin, os.Stderr, typ.String()+"を打刻しますか?")
+			if err != nil {
+				log.Fatalln(err)
+			}
+			if !ok {
+				log.Fatalln("canceled")
+			}
+		}
+		postStamp(ctx, typ, at)
 	},
 }
 
 var stampGoToWorkCmd = &cobra.Command{
 	Use:   "work-in",
 	Short: "出勤の打刻",
-	Long:  "出勤の打刻",
+	Long:  "出勤の打刻\n" + stampTimeHelp,
+	Args:  cobra.NoArgs,
 	Run: func(cmd *cobra.Command, args []string) {
-		ctx := context.Background()
-		p := akashi.PostStampParam{
-			LoginCompanyCode: loginCompanyCode,
-			Token:            accessToken,
-			Type:             akashi.StampTypeGoToWork,
-		}
-		res, err := akashi.PostStamp(ctx, p)
-		if err != nil {
-			log.Fatalln(err)
-			os.Exit(1)
-		}
-		printStampPostResponse(res)
+		stampAs(akashi.StampTypeGoToWork)
 	},
 }
 
 var stampLeaveWorkCmd = &cobra.Command{
 	Use:   "work-out",
 	Short: "退勤の打刻",
-	Long:  "退勤の打刻",
+	Long:  "退勤の打刻\n" + stampTimeHelp,
+	Args:  cobra.NoArgs,
 	Run: func(cmd *cobra.Command, args []string) {
-		ctx := context.Background()
-		p := akashi.PostStampParam{
-			LoginCompanyCode: loginCompanyCode,
-			Token:            accessToken,
-			Type:             akashi.StampTypeLeaveWork,
-		}
-		res, err := akashi.PostStamp(ctx, p)
-		if err != nil {
-			log.Fatalln(err)
-			os.Exit(1)
-		}
-		printStampPostResponse(res)
+		stampAs(akashi.StampTypeLeaveWork)
 	},
 }
 
 var stampBreakCmd = &cobra.Command{
 	Use:   "break-in",
 	Short: "休憩入りの打刻",
-	Long:  "休憩入りの打刻",
+	Long:  "休憩入りの打刻\n" + stampTimeHelp,
+	Args:  cobra.NoArgs,
 	Run: func(cmd *cobra.Command, args []string) {
-		ctx := context.Background()
-		p := akashi.PostStampParam{
-			LoginCompanyCode: loginCompanyCode,
-			Token:            accessToken,
-			Type:             akashi.StampTypeBreak,
-		}
-		res, err := akashi.PostStamp(ctx, p)
-		if err != nil {
-			log.Fatalln(err)
-			os.Exit(1)
-		}
-		printStampPostResponse(res)
+		stampAs(akashi.StampTypeBreak)
 	},
 }
 
 var stampBreakReturnCmd = &cobra.Command{
 	Use:   "break-out",
 	Short: "休憩戻りの打刻",
-	Long:  "休憩戻りの打刻",
+	Long:  "休憩戻りの打刻\n" + stampTimeHelp,
+	Args:  cobra.NoArgs,
 	Run: func(cmd *cobra.Command, args []string) {
-		ctx := context.Background()
-		p := akashi.PostStampParam{
-			LoginCompanyCode: loginCompanyCode,
-			Token:            accessToken,
-			Type:             akashi.StampTypeBreakReturn,
-		}
-		res, err := akashi.PostStamp(ctx, p)
+		stampAs(akashi.StampTypeBreakReturn)
+	},
+}
+
+// stampAs 種別を指定して打刻する
+// --force の指定がない場合は今日の打刻から判定した状態に合わない打刻を拒否する
+func stampAs(typ akashi.StampType) {
+	ctx := context.Background()
+	at, err := stampTime(time.Now())
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if !forceStamp {
+		day, err := today(ctx, at)
 		if err != nil {
+			if queueEnabled() && akashi.IsNetworkError(err) {
+				// 通信できない場合は状態を確認せずにキューに保存する
+				enqueueStamp(typ, at, err)
+				return
+			}
 			log.Fatalln(err)
-			os.Exit(1)
 		}
-		printStampPostResponse(res)
-	},
+		if _, err := day.State().Apply(typ); err != nil {
+			log.Fatalln(err, "(use --force to stamp anyway)")
+		}
+	}
+	postStamp(ctx, typ, at)
+}
+
+// postStamp 打刻して結果を出力する
+// atがゼロ値の場合はサーバでの打刻日時で記録する。キューが有効な場合は通信の失敗時にキューに保存する
+func postStamp(ctx context.Context, typ akashi.StampType, at time.Time) {
+	p := akashi.PostStampParam{
+		LoginCompanyCode: loginCompanyCode,
+		Token:            accessToken,
+		Type:             typ,
+	}
+	if !at.IsZero() {
+		p.StampedAt = akashi.NewAkTime(at)
+		p.Timezone = akashi.FormatTimezone(at)
+	}
+	if cfg != nil && cfg.Stamp != nil && cfg.Stamp.DedupeWindow != "" {
+		d, err := time.ParseDuration(cfg.Stamp.DedupeWindow)
+		if err != nil {
+			log.Fatalln("stamp.dedupe_window:", err)
+		}
+		p.DedupeWindow = d
+	}
+	res, err := akashi.PostStamp(ctx, p)
+	if err != nil {
+		if queueEnabled() && akashi.IsNetworkError(err) {
+			enqueueStamp(typ, at, err)
+			return
+		}
+		log.Fatalln(err)
+	}
+	if res.Duplicate {
+		fmt.Fprintln(os.Stderr, duplicateNotice(res))
+	}
+	printStampPostResponse(res)
+}
+
+// duplicateNotice 重複のため打刻しなかったことの通知
+// 既存の打刻に打刻日時がない場合は日時を省く
+func duplicateNotice(res akashi.PostStampResponse) string {
+	if res.StampedAt == nil || res.StampedAt.IsZero() {
+		return fmt.Sprintf("%s is already stamped; skipped", res.Type)
+	}
+	return fmt.Sprintf("%s is already stamped at %s; skipped", res.Type, res.StampedAt.In(akashi.Location).Format(akashi.ReturnDateFormat))
+}
+
+// today 打刻した時刻がnowの日のnowまでの打刻から勤怠記録を作成する
+// nowがゼロ値の場合は現在時刻。打刻がない場合は打刻のない勤怠記録を返す
+func today(ctx context.Context, now time.Time) (attendance.Day, error) {
+	if now.IsZero() {
+		now = time.Now()
+	}
+	r, err := resolveDateRange("today", "", now, akashi.Location)
+	if err != nil {
+		return attendance.Day{}, err
+	}
+	res, err := fetchStampsAt(ctx, r, now)
+	if err != nil {
+		return attendance.Day{}, err
+	}
+	var stamps []akashi.Stamp
+	for _, s := range res.Stamps {
+		if !s.ClientTime().After(now) {
+			stamps = append(stamps, s)
+		}
+	}
+	b := attendance.Builder{Location: akashi.Location}
+	for _, d := range b.Build(res.StaffID, stamps) {
+		if d.Date.Equal(r.Start) {
+			return d, nil
+		}
+	}
+	return attendance.Day{StaffID: res.StaffID, Date: r.Start}, nil
+}
+
+// confirm promptを表示してrから読んだ回答がyesかどうかを返す
+func confirm(r io.Reader, w io.Writer, prompt string) (bool, error) {
+	fmt.Fprintf(w, "%s [y/N]: ", prompt)
+	line, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return false, err
+	}
+	switch strings.ToLower(strings.TrimSpace(line)) {
+	case "y", "yes":
+		return true, nil
+	}
+	return false, nil
+}
+
+// stampPostView 打刻結果の出力形式
+type stampPostView struct {
+	LoginCompanyCode string     `json:"login_company_code"`
+	StaffID          int        `json:"staff_id"`
+	Type             int        `json:"type"`
+	TypeName         string     `json:"type_name"`
+	StampedAt        *time.Time `json:"stamped_at"`
 }
 
 func printStampPostResponse(res akashi.PostStampResponse) {
-	fmt.Println("企業ID:", res.LoginCompanyCode)
-	fmt.Println("従業員ID:", res.StaffID)
-	fmt.Println("打刻種別:", res.Type)
-	fmt.Println("打刻日時:", res.StampedAt)
+	v := stampPostView{
+		LoginCompanyCode: res.LoginCompanyCode,
+		StaffID:          res.StaffID,
+		Type:             int(res.Type),
+		TypeName:         res.Type.String(),
+		StampedAt:        akTime(res.StampedAt),
+	}
+	if err := out.render(v); err != nil {
+		log.Fatalln(err)
+	}
+}
+
+// stampTimeHelp 打刻日時の指定の説明
+const stampTimeHelp = `
+--at で打刻日時を、--timezone で打刻したタイムゾーンを指定できます。
+--at の日時は --timezone のタイムゾーンで解釈し、そのタイムゾーンの時刻として送信します。
+(--akashi-timezone はAKASHIの日時のタイムゾーンで、打刻したタイムゾーンとは別に指定できます)
+  aka-cli stamp work-out --at "yesterday 18:30"
+  aka-cli stamp work-in --at 09:05 --timezone America/New_York
+指定できる日時は設定ファイルのstampで制限します(既定は過去48時間から未来5分まで)。
+  stamp:
+    max_past: 72h
+    max_future: 1m
+設定ファイルのstampでdedupe_windowを指定すると、同じ種別の打刻がその時間内にある場合は打刻せずにその打刻を表示します。`
+
+// 打刻日時の指定の既定の範囲
+const (
+	defaultMaxPast   = 48 * time.Hour
+	defaultMaxFuture = 5 * time.Minute
+)
+
+// stampTime --at と --timezone から打刻日時を決定する
+// どちらも指定がない場合はサーバでの打刻日時を使うのでゼロ値を返す
+func stampTime(now time.Time) (time.Time, error) {
+	if stampedAt == "" && stampTimezone == "" {
+		return time.Time{}, nil
+	}
+	loc, err := stampLocation(stampTimezone)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("--timezone: %w", err)
+	}
+	at := now
+	if stampedAt != "" {
+		if at, err = parseTimeExpr(stampedAt, now, loc); err != nil {
+			return time.Time{}, fmt.Errorf("--at: %w", err)
+		}
+	}
+	var sc StampConfig
+	if cfg != nil && cfg.Stamp != nil {
+		sc = *cfg.Stamp
+	}
+	if err := checkClockSkew(at, now, sc); err != nil {
+		return time.Time{}, fmt.Errorf("--at: %w", err)
+	}
+	return at.In(loc).Truncate(time.Second), nil
+}
+
+// stampLocation 打刻のタイムゾーン
+// IANAのタイムゾーン名と±HH:MM形式を受け付け、未指定の場合はakashi.Location
+func stampLocation(name string) (*time.Location, error) {
+	if name == "" {
+		return akashi.Location, nil
+	}
+	if strings.HasPrefix(name, "+") || strings.HasPrefix(name, "-") {
+		return akashi.ParseTimezone(name)
+	}
+	return time.LoadLocation(name)
+}
+
+// checkClockSkew 打刻日時が現在時刻から設定の範囲内か
+func checkClockSkew(at, now time.Time, sc StampConfig) error {
+	maxPast, maxFuture := defaultMaxPast, defaultMaxFuture
+	if sc.MaxPast != "" {
+		d, err := time.ParseDuration(sc.MaxPast)
+		if err != nil {
+			return fmt.Errorf("stamp.max_past: %w", err)
+		}
+		maxPast = d
+	}
+	if sc.MaxFuture != "" {
+		d, err := time.ParseDuration(sc.MaxFuture)
+		if err != nil {
+			return fmt.Errorf("stamp.max_future: %w", err)
+		}
+		maxFuture = d
+	}
+	if d := at.Sub(now); d > maxFuture {
+		return fmt.Errorf("%s is %s in the future (max %s)", at.Format(time.RFC3339), d.Round(time.Second), maxFuture)
+	}
+	if d := now.Sub(at); d > maxPast {
+		return fmt.Errorf("%s is %s in the past (max %s)", at.Format(time.RFC3339), d.Round(time.Second), maxPast)
+	}
+	return nil
 }
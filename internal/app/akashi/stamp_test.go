@@ -0,0 +1,80 @@
+package akashi
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"hapoon/go-akashi/pkg/akashi"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfirm(t *testing.T) {
+	tests := []struct {
+		input string
+		want  bool
+	}{
+		{"y\n", true},
+		{" YES \n", true},
+		{"n\n", false},
+		{"\n", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		var w bytes.Buffer
+		ok, err := confirm(strings.NewReader(tt.input), &w, "出勤を打刻しますか?")
+		assert.NoError(t, err)
+		assert.Equal(t, tt.want, ok, "%q", tt.input)
+		assert.Equal(t, "出勤を打刻しますか? [y/N]: ", w.String())
+	}
+}
+
+func TestCheckClockSkew(t *testing.T) {
+	now := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	assert.NoError(t, checkClockSkew(now.Add(-47*time.Hour), now, StampConfig{}))
+	assert.NoError(t, checkClockSkew(now.Add(5*time.Minute), now, StampConfig{}))
+	assert.EqualError(t, checkClockSkew(now.Add(6*time.Minute), now, StampConfig{}),
+		"2026-10-16T09:06:00Z is 6m0s in the future (max 5m0s)")
+	assert.Error(t, checkClockSkew(now.Add(-49*time.Hour), now, StampConfig{}))
+	assert.NoError(t, checkClockSkew(now.Add(-49*time.Hour), now, StampConfig{MaxPast: "72h"}))
+	assert.Error(t, checkClockSkew(now, now, StampConfig{MaxFuture: "soon"}))
+}
+
+func TestStampLocation(t *testing.T) {
+	loc, err := stampLocation("-04:00")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "-04:00", akashi.FormatTimezone(time.Date(2026, 10, 16, 0, 0, 0, 0, loc)))
+	}
+	loc, err = stampLocation("")
+	assert.NoError(t, err)
+	assert.Equal(t, akashi.Location, loc)
+	_, err = stampLocation("Mars/Olympus")
+	assert.Error(t, err)
+}
+
+func TestStampTimezoneFlags(t *testing.T) {
+	defer func() { timezoneName, stampTimezone, stampedAt = "", "", "" }()
+	cmd := stampGoToWorkCmd
+	err := cmd.ParseFlags([]string{"--akashi-timezone", "UTC", "--timezone", "America/New_York", "--at", "09:05"})
+	assert.NoError(t, err)
+	// AKASHIのタイムゾーンと打刻したタイムゾーンは別々に指定できる
+	assert.Equal(t, "UTC", timezoneName)
+	assert.Equal(t, "America/New_York", stampTimezone)
+	assert.NotNil(t, cmd.InheritedFlags().Lookup("akashi-timezone"))
+	assert.NotNil(t, cmd.LocalNonPersistentFlags().Lookup("timezone"))
+	assert.Nil(t, cmd.InheritedFlags().Lookup("timezone"))
+
+	now := time.Date(2026, 10, 16, 14, 0, 0, 0, time.UTC)
+	at, err := stampTime(now)
+	assert.NoError(t, err)
+	assert.Equal(t, "2026-10-16T09:05:00-04:00", at.Format(time.RFC3339))
+}
+
+func TestDuplicateNotice(t *testing.T) {
+	res := akashi.PostStampResponse{Type: akashi.StampTypeGoToWork, Duplicate: true}
+	assert.Equal(t, "出勤 is already stamped; skipped", duplicateNotice(res))
+	res.StampedAt = akashi.NewAkTime(time.Date(2026, 10, 16, 9, 0, 0, 0, akashi.Location))
+	assert.Equal(t, "出勤 is already stamped at 2026/10/16 09:00:00; skipped", duplicateNotice(res))
+}
@@ -0,0 +1,223 @@
+package akashi
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"hapoon/go-akashi/pkg/akashi"
+	"hapoon/go-akashi/pkg/akashi/attendance"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	// status
+	shortStatus bool
+)
+
+// statusの終了コード
+const (
+	exitWorking    = 0
+	exitOnBreak    = 2
+	exitNotStarted = 3
+	exitFinished   = 4
+)
+
+func init() {
+	statusCmd.Flags().BoolVar(&shortStatus, "short", false, "Print a single line for shell prompts and status bars")
+	rootCmd.AddCommand(statusCmd)
+}
+
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "現在の勤務状態",
+	Long: `今日の打刻から現在の勤務状態を表示します。
+状態・最後の打刻からの経過時間・今日の実労働時間と休憩時間・今日のアラートを出力します。
+日付を判別できないアラートは日付で絞り込まずに出力します。
+勤務中と休憩中の時間は現在時刻までを含みます。
+
+--short はシェルのプロンプトやtmuxのステータスバー向けに1行で出力します。
+  勤務中 実働3:25 休憩0:45 最終打刻から1:05
+
+終了コードは状態を表します。
+  0  勤務中
+  2  休憩中
+  3  未出勤
+  4  退勤済
+  1  エラー`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := context.Background()
+		now := time.Now()
+		day, err := today(ctx, now)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		alerts, err := akashi.GetAlerts(ctx, akashi.GetAlertParam{
+			LoginCompanyCode: loginCompanyCode,
+			Token:            accessToken,
+		})
+		if err != nil {
+			log.Fatalln(err)
+		}
+		v := newStatusView(day, alerts.Alerts, now)
+		if shortStatus {
+			fmt.Println(v.short())
+		} else if err := out.render(v); err != nil {
+			log.Fatalln(err)
+		}
+		os.Exit(statusExitCode(day.State()))
+	},
+}
+
+// statusView 勤務状態の出力形式
+type statusView struct {
+	State          string     `json:"state"`
+	StateName      string     `json:"state_name"`
+	LastStampType  string     `json:"last_stamp_type"`
+	LastStampedAt  *time.Time `json:"last_stamped_at"`
+	SinceLastStamp string     `json:"since_last_stamp"`
+	SinceSeconds   int64      `json:"since_seconds"`
+	WorkMinutes    int        `json:"work_minutes"`
+	BreakMinutes   int        `json:"break_minutes"`
+	AlertCount     int        `json:"alert_count"`
+	Alerts         string     `json:"alerts"`
+}
+
+// stateCodes 状態のスクリプト向けの名前
+var stateCodes = map[attendance.State]string{
+	attendance.StateNotStarted: "not_started",
+	attendance.StateWorking:    "working",
+	attendance.StateOnBreak:    "on_break",
+	attendance.StateFinished:   "finished",
+}
+
+// newStatusView 今日の勤怠記録とアラートから現在の勤務状態を作成する
+func newStatusView(day attendance.Day, alerts []akashi.Alert, now time.Time) statusView {
+	state := day.State()
+	v := statusView{State: stateCodes[state], StateName: state.String()}
+	if n := len(day.Stamps); n > 0 {
+		last := day.Stamps[n-1]
+		v.LastStampType = last.Type.String()
+		at := last.ClientTime().In(akashi.Location)
+		v.LastStampedAt = &at
+		since := now.Sub(at)
+		if since < 0 {
+			since = 0
+		}
+		v.SinceLastStamp = clockDuration(since)
+		v.SinceSeconds = int64(since / time.Second)
+	}
+
+	// 勤務中・休憩中は現在時刻で休憩戻・退勤したものとして集計する
+	stamps := append([]akashi.Stamp(nil), day.Stamps...)
+	switch state {
+	case attendance.StateOnBreak:
+		stamps = append(stamps, akashi.Stamp{Type: akashi.StampTypeBreakReturn, StampedAt: akashi.NewAkTime(now)})
+		fallthrough
+	case attendance.StateWorking:
+		stamps = append(stamps, akashi.Stamp{Type: akashi.StampTypeLeaveWork, StampedAt: akashi.NewAkTime(now)})
+	}
+	b := attendance.Builder{Location: akashi.Location}
+	for _, d := range b.Build(day.StaffID, stamps) {
+		v.WorkMinutes += int(d.Net / time.Minute)
+		v.BreakMinutes += int(d.Break / time.Minute)
+	}
+
+	var names []string
+	for _, a := range alerts {
+		// 日付を判別できないアラートは見落とさないように表示する
+		if d, err := alertDate(a, now, akashi.Location); err != nil || (!day.Date.IsZero() && d.Equal(day.Date)) {
+			names = append(names, a.AlertType.String())
+		}
+	}
+	v.AlertCount = len(names)
+	v.Alerts = strings.Join(names, "、")
+	return v
+}
+
+// alertDate アラートの発生した日をlocの0時で返す
+//
+// dateは日(16、16日)で、monthは月度(2026/10、202610、10月など)を表す。
+// monthに年がない場合はnow以前で直近のその月とする。dateが年月日の場合はそのまま利用する
+func alertDate(a akashi.Alert, now time.Time, loc *time.Location) (time.Time, error) {
+	date := strings.TrimSpace(a.Date)
+	if day, err := strconv.Atoi(strings.TrimSuffix(date, "日")); err == nil && day >= 1 && day <= 31 {
+		year, month, err := alertMonth(a.Month, now.In(loc))
+		if err != nil {
+			return time.Time{}, err
+		}
+		t := time.Date(year, month, day, 0, 0, 0, 0, loc)
+		if t.Day() != day {
+			return time.Time{}, fmt.Errorf("invalid alert date %q in month %q", a.Date, a.Month)
+		}
+		return t, nil
+	}
+	for _, layout := range []string{"2006/01/02", "2006/1/2", "2006-01-02", "20060102"} {
+		if t, err := time.ParseInLocation(layout, date, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid alert date %q", a.Date)
+}
+
+// alertMonth アラートの月度を年と月に変換する
+func alertMonth(s string, now time.Time) (int, time.Month, error) {
+	s = strings.TrimSpace(s)
+	for _, layout := range []string{"2006/01", "2006/1", "2006-01", "200601"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.Year(), t.Month(), nil
+		}
+	}
+	m, err := strconv.Atoi(strings.TrimSuffix(s, "月"))
+	if err != nil || m < 1 || m > 12 {
+		return 0, 0, fmt.Errorf("invalid alert month %q", s)
+	}
+	year := now.Year()
+	if time.Month(m) > now.Month() {
+		year--
+	}
+	return year, time.Month(m), nil
+}
+
+// short 1行の表示
+func (v statusView) short() string {
+	parts := []string{v.StateName}
+	if v.WorkMinutes > 0 || v.BreakMinutes > 0 {
+		parts = append(parts,
+			"実働"+clockDuration(time.Duration(v.WorkMinutes)*time.Minute),
+			"休憩"+clockDuration(time.Duration(v.BreakMinutes)*time.Minute))
+	}
+	if v.LastStampedAt != nil {
+		parts = append(parts, "最終打刻から"+v.SinceLastStamp)
+	}
+	if v.AlertCount > 0 {
+		parts = append(parts, fmt.Sprintf("アラート%d件", v.AlertCount))
+	}
+	return strings.Join(parts, " ")
+}
+
+// clockDuration 時間をH:MMで表す
+func clockDuration(d time.Duration) string {
+	m := int(d / time.Minute)
+	return fmt.Sprintf("%d:%02d", m/60, m%60)
+}
+
+// statusExitCode 状態に対応する終了コード
+func statusExitCode(s attendance.State) int {
+	switch s {
+	case attendance.StateOnBreak:
+		return exitOnBreak
+	case attendance.StateNotStarted:
+		return exitNotStarted
+	case attendance.StateFinished:
+		return exitFinished
+	default:
+		return exitWorking
+	}
+}
@@ -0,0 +1,73 @@
+package akashi
+
+import (
+	"testing"
+	"time"
+
+	"hapoon/go-akashi/pkg/akashi"
+	"hapoon/go-akashi/pkg/akashi/attendance"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewStatusView(t *testing.T) {
+	at := func(hour, min int) *akashi.AkTime {
+		return akashi.NewAkTime(time.Date(2026, 10, 16, hour, min, 0, 0, akashi.Location))
+	}
+	date := time.Date(2026, 10, 16, 0, 0, 0, 0, akashi.Location)
+	stamps := []akashi.Stamp{
+		{Type: akashi.StampTypeGoToWork, StampedAt: at(9, 0)},
+		{Type: akashi.StampTypeBreak, StampedAt: at(12, 0)},
+	}
+	days := attendance.Builder{Location: akashi.Location}.Build(1, stamps)
+	alerts := []akashi.Alert{
+		{Month: "2026/10", Date: "16", AlertType: akashi.AlertTypeLateness},
+		{Month: "2026/10", Date: "15", AlertType: akashi.AlertTypeForgetStamp},
+		{Month: "2026/09", Date: "16", AlertType: akashi.AlertTypeMayBeAbsent},
+	}
+	now := time.Date(2026, 10, 16, 12, 30, 0, 0, akashi.Location)
+
+	v := newStatusView(days[0], alerts, now)
+	assert.Equal(t, "on_break", v.State)
+	assert.Equal(t, "休憩入", v.LastStampType)
+	assert.Equal(t, int64(30*60), v.SinceSeconds)
+	assert.Equal(t, 180, v.WorkMinutes)
+	assert.Equal(t, 30, v.BreakMinutes)
+	assert.Equal(t, "遅刻", v.Alerts)
+	assert.Equal(t, "休憩中 実働3:00 休憩0:30 最終打刻から0:30 アラート1件", v.short())
+	assert.Equal(t, exitOnBreak, statusExitCode(days[0].State()))
+
+	// 日付を判別できないアラートは絞り込まずに表示する
+	v = newStatusView(days[0], []akashi.Alert{{Month: "今月", Date: "16", AlertType: akashi.AlertTypeForgetStamp}}, now)
+	assert.Equal(t, 1, v.AlertCount)
+
+	v = newStatusView(attendance.Day{StaffID: 1, Date: date}, nil, now)
+	assert.Equal(t, "not_started", v.State)
+	assert.Equal(t, "未出勤", v.short())
+	assert.Equal(t, exitNotStarted, statusExitCode(attendance.StateNotStarted))
+}
+
+func TestAlertDate(t *testing.T) {
+	now := time.Date(2026, 1, 10, 12, 0, 0, 0, akashi.Location)
+	tests := []struct {
+		month, date string
+		want        time.Time
+	}{
+		{"2026/01", "9", time.Date(2026, 1, 9, 0, 0, 0, 0, akashi.Location)},
+		{"202601", "09", time.Date(2026, 1, 9, 0, 0, 0, 0, akashi.Location)},
+		{"1", "9日", time.Date(2026, 1, 9, 0, 0, 0, 0, akashi.Location)},
+		// 年がない場合は直近のその月
+		{"12月", "31", time.Date(2025, 12, 31, 0, 0, 0, 0, akashi.Location)},
+		{"2025/12", "2025/12/30", time.Date(2025, 12, 30, 0, 0, 0, 0, akashi.Location)},
+	}
+	for _, tt := range tests {
+		got, err := alertDate(akashi.Alert{Month: tt.month, Date: tt.date}, now, akashi.Location)
+		if assert.NoError(t, err, "%s %s", tt.month, tt.date) {
+			assert.True(t, tt.want.Equal(got), "%s %s: got %v", tt.month, tt.date, got)
+		}
+	}
+	for _, a := range []akashi.Alert{{Month: "2026/02", Date: "30"}, {Month: "13", Date: "1"}, {Month: "2026/01", Date: ""}} {
+		_, err := alertDate(a, now, akashi.Location)
+		assert.Error(t, err, "%+v", a)
+	}
+}
@@ -1,17 +1,37 @@
 package akashi
 
 import (
+	"bufio"
 	"context"
+	"errors"
 	"fmt"
-	"hapoon/go-akashi/pkg/akashi"
+	"io"
 	"log"
 	"os"
+	"strings"
+	"time"
+
+	"hapoon/go-akashi/pkg/akashi"
 
 	"github.com/spf13/cobra"
 )
 
+var (
+	// token reissue
+	noSave bool
+	// token status
+	warnBefore time.Duration
+	// token set
+	tokenExpiredAt string
+)
+
 func init() {
+	tokenReissueCmd.Flags().BoolVar(&noSave, "no-save", false, "Do not save the reissued token to the profile")
+	tokenStatusCmd.Flags().DurationVar(&warnBefore, "warn-before", 72*time.Hour, "Warn when the token expires within this duration")
+	tokenSetCmd.Flags().StringVar(&tokenExpiredAt, "expired-at", "", "Expiration date of the token (yyyy/mm/dd HH:MM:SS)")
 	tokenCmd.AddCommand(tokenReissueCmd)
+	tokenCmd.AddCommand(tokenStatusCmd)
+	tokenCmd.AddCommand(tokenSetCmd)
 	rootCmd.AddCommand(tokenCmd)
 }
 
@@ -28,7 +48,8 @@ var tokenReissueCmd = &cobra.Command{
 	Use:   "reissue",
 	Short: "アクセストークンの再発行",
 	Long: `トークンにて認証した従業員のアクセストークンを再発行します。
-再発行時に有効期限切れのトークンが存在する場合、自動的に削除されます。`,
+再発行時に有効期限切れのトークンが存在する場合、自動的に削除されます。
+再発行したトークンと有効期限は利用中のプロファイルに保存されます。`,
 	Run: func(cmd *cobra.Command, args []string) {
 		if verbose {
 			log.Println("args:", args)
@@ -43,10 +64,262 @@ var tokenReissueCmd = &cobra.Command{
 			log.Fatalln(err)
 			os.Exit(1)
 		}
-		fmt.Println("企業ID:", res.LoginCompanyCode)
-		fmt.Println("従業員ID:", res.StaffID)
-		fmt.Println("agency_manager_id:", res.AgencyManagerID)
-		fmt.Println("アクセストークン:", res.Token)
-		fmt.Println("有効期限:", res.ExpiredAt)
+		v := tokenView{
+			LoginCompanyCode: res.LoginCompanyCode,
+			StaffID:          res.StaffID,
+			AgencyManagerID:  res.AgencyManagerID,
+			Token:            res.Token,
+			ExpiredAt:        akTime(res.ExpiredAt),
+		}
+		if err := out.render(v); err != nil {
+			log.Fatalln(err)
+		}
+		if noSave {
+			return
+		}
+		// 旧トークンは既に無効なので保存に失敗した場合も新しいトークンは表示しておく
+		if err := saveReissuedToken(res); err != nil {
+			log.Fatalln("failed to save token:", err)
+		}
+		fmt.Fprintln(os.Stderr, "保存先プロファイル:", active.Profile)
+		warnOverridden()
+	},
+}
+
+var tokenStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "アクセストークンの状態",
+	Long: `アクセストークンの従業員ID・企業ID・有効期限までの残り時間を表示します。
+有効期限が --warn-before 以内の場合は警告を表示します。
+有効期限はtoken reissueまたはtoken set --expired-atで保存したものを利用します。`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := context.Background()
+		p := akashi.GetStaffParam{
+			LoginCompanyCode: loginCompanyCode,
+			Token:            accessToken,
+		}
+		res, err := akashi.GetStaff(ctx, p)
+		expired, invalid := errors.Is(err, akashi.ErrTokenExpired), errors.Is(err, akashi.ErrInvalidToken)
+		switch {
+		case expired && invalid:
+			log.Fatalln("アクセストークンが無効か有効期限が切れています")
+		case expired:
+			log.Fatalln("アクセストークンの有効期限が切れています")
+		case invalid:
+			log.Fatalln("アクセストークンが無効です")
+		case err != nil:
+			log.Fatalln(err)
+		}
+		v := tokenStatusView{
+			Profile:          active.Profile,
+			LoginCompanyCode: res.LoginCompanyCode,
+		}
+		if len(res.Staffs) > 0 {
+			v.StaffID = res.Staffs[0].ID
+		}
+		if c, err := credentials.Get(active.Profile); err == nil && c.Token == accessToken && c.ExpiredAt != nil {
+			left := time.Until(*c.ExpiredAt)
+			expiredAt := c.ExpiredAt.In(akashi.Location)
+			v.ExpiredAt = &expiredAt
+			v.ExpiresIn = formatDuration(left)
+			v.ExpiresInSeconds = int64(left / time.Second)
+			if left < warnBefore {
+				fmt.Fprintln(os.Stderr, "警告: アクセストークンの有効期限が近づいています。token reissueで再発行してください")
+			}
+		}
+		if err := out.render(v); err != nil {
+			log.Fatalln(err)
+		}
+	},
+}
+
+var tokenSetCmd = &cobra.Command{
+	Use:   "set",
+	Short: "アクセストークンの保存",
+	Long: `標準入力から読み込んだアクセストークンを利用中のプロファイルに保存します。
+シェルの履歴にトークンが残らないように、パイプまたは対話入力で指定してください。
+
+  pbpaste | aka-cli token set --expired-at "2026/12/31 23:59:59"`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		var r io.Reader = os.Stdin
+		if isTerminal(os.Stdin) {
+			// 画面やスクロールバックに残らないようにエコーせずに読み込む
+			s, err := readPassphrase("アクセストークン: ")
+			if err != nil {
+				log.Fatalln(err)
+			}
+			r = strings.NewReader(s)
+		}
+		token, err := readToken(r)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		var expiredAt *time.Time
+		if tokenExpiredAt != "" {
+			t, err := akashi.ParseAkTime(tokenExpiredAt)
+			if err != nil {
+				log.Fatalln(err)
+			}
+			expiredAt = &t
+		}
+		if err := setToken(context.Background(), token, expiredAt); err != nil {
+			log.Fatalln("failed to save token:", err)
+		}
+		fmt.Fprintln(os.Stderr, "保存先プロファイル:", active.Profile)
 	},
 }
+
+// tokenView 再発行したアクセストークンの出力形式
+type tokenView struct {
+	LoginCompanyCode string     `json:"login_company_code"`
+	StaffID          int        `json:"staff_id"`
+	AgencyManagerID  int        `json:"agency_manager_id"`
+	Token            string     `json:"token"`
+	ExpiredAt        *time.Time `json:"expired_at"`
+}
+
+// tokenStatusView アクセストークンの状態の出力形式
+// 有効期限が不明な場合はexpired_at以降が空になる
+type tokenStatusView struct {
+	Profile          string     `json:"profile"`
+	LoginCompanyCode string     `json:"login_company_code"`
+	StaffID          int        `json:"staff_id"`
+	ExpiredAt        *time.Time `json:"expired_at"`
+	ExpiresIn        string     `json:"expires_in"`
+	ExpiresInSeconds int64      `json:"expires_in_seconds"`
+}
+
+// saveToken アクセストークンを利用中のプロファイルの保存先に保存する
+//
+// 認証情報を保存してからプロファイルを保存し、プロファイルの保存に失敗した場合は認証情報を元に戻す。
+// staffIDが0(不明)の場合は保存済みの従業員IDを変更しない
+func saveToken(companyCode, token string, expiredAt *time.Time, staffID int) error {
+	name := active.Profile
+	prev, prevErr := credentials.Get(name)
+	if prevErr != nil && !errors.Is(prevErr, errCredentialNotFound) {
+		return prevErr
+	}
+	old, existed := cfg.Profiles[name]
+	var backup Profile
+	if existed {
+		backup = *old
+	}
+	current := cfg.CurrentProfile
+
+	if err := credentials.Set(name, Credential{Token: token, ExpiredAt: expiredAt}); err != nil {
+		return err
+	}
+	p := cfg.activeProfile(name)
+	if companyCode != "" {
+		p.CompanyCode = companyCode
+	}
+	if staffID != 0 {
+		p.StaffID = staffID
+	}
+	err := cfg.save(configPath)
+	if err == nil {
+		return nil
+	}
+
+	if existed {
+		*p = backup
+	} else {
+		delete(cfg.Profiles, name)
+		cfg.CurrentProfile = current
+	}
+	var rerr error
+	if prevErr == nil {
+		rerr = credentials.Set(name, prev)
+	} else {
+		rerr = credentials.Delete(name)
+	}
+	if rerr != nil {
+		return fmt.Errorf("%w (and failed to restore the previous credential: %v)", err, rerr)
+	}
+	return err
+}
+
+// saveReissuedToken 再発行したアクセストークンを利用中のプロファイルに保存する
+func saveReissuedToken(res akashi.PostTokenReissueResponse) error {
+	var expiredAt *time.Time
+	if res.ExpiredAt != nil {
+		t := res.ExpiredAt.Time
+		expiredAt = &t
+	}
+	return saveToken(res.LoginCompanyCode, res.Token, expiredAt, res.StaffID)
+}
+
+// useReissuingClient 有効期限が近づいたアクセストークンを自動的に再発行するクライアントをDefaultClientにする
+// 有効期限は保存先に保存したものを利用する。再発行したトークンの保存はonReissueで行う
+func useReissuingClient(onReissue func(akashi.PostTokenReissueResponse) error) *akashi.ReissueTokenSource {
+	tok := akashi.Token{Value: accessToken}
+	if c, err := credentials.Get(active.Profile); err == nil && c.Token == accessToken && c.ExpiredAt != nil {
+		tok.ExpiredAt = *c.ExpiredAt
+	}
+	ts := akashi.NewReissueTokenSource(akashi.NewClient(clientOptions...), loginCompanyCode, tok)
+	ts.OnReissue = onReissue
+	akashi.DefaultClient = akashi.NewClient(append(clientOptions[:len(clientOptions):len(clientOptions)], akashi.WithTokenSource(ts))...)
+	return ts
+}
+
+// setToken 入力されたアクセストークンを保存する
+// 従業員IDはトークンで従業員情報を取得できた場合のみ更新する
+func setToken(ctx context.Context, token string, expiredAt *time.Time) error {
+	staffID := 0
+	res, err := akashi.GetStaff(ctx, akashi.GetStaffParam{
+		LoginCompanyCode: loginCompanyCode,
+		Token:            token,
+	})
+	if err == nil && len(res.Staffs) > 0 {
+		staffID = res.Staffs[0].ID
+	}
+	return saveToken(loginCompanyCode, token, expiredAt, staffID)
+}
+
+// warnOverridden 保存したトークンがフラグや環境変数で上書きされる場合に警告する
+func warnOverridden() {
+	if flagSettings.Token != "" || os.Getenv(envToken) != "" {
+		fmt.Fprintln(os.Stderr, "警告: --token または "+envToken+" が指定されているため、保存したトークンは利用されません")
+	}
+}
+
+// readToken 1行目をアクセストークンとして読み込む
+func readToken(r io.Reader) (string, error) {
+	line, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+	token := strings.TrimSpace(line)
+	if token == "" {
+		return "", errors.New("token is empty")
+	}
+	return token, nil
+}
+
+// isTerminal fが端末かどうか
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return fi.Mode()&os.ModeCharDevice != 0
+}
+
+// formatDuration 残り時間を日・時間・分で表す
+func formatDuration(d time.Duration) string {
+	if d <= 0 {
+		return "期限切れ"
+	}
+	d = d.Round(time.Minute)
+	days := d / (24 * time.Hour)
+	d -= days * 24 * time.Hour
+	hours := d / time.Hour
+	d -= hours * time.Hour
+	minutes := d / time.Minute
+	if days > 0 {
+		return fmt.Sprintf("%d日%d時間%d分", days, hours, minutes)
+	}
+	return fmt.Sprintf("%d時間%d分", hours, minutes)
+}
@@ -0,0 +1,161 @@
+package akashi
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"hapoon/go-akashi/pkg/akashi"
+	"hapoon/go-akashi/pkg/akashi/akashitest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadToken(t *testing.T) {
+	token, err := readToken(strings.NewReader("  new-token \nignored\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, "new-token", token)
+
+	token, err = readToken(strings.NewReader("no-newline"))
+	assert.NoError(t, err)
+	assert.Equal(t, "no-newline", token)
+
+	_, err = readToken(strings.NewReader("\n"))
+	assert.Error(t, err)
+}
+
+func TestFormatDuration(t *testing.T) {
+	tests := map[time.Duration]string{
+		-time.Minute:                    "期限切れ",
+		90 * time.Minute:                "1時間30分",
+		49*time.Hour + 5*time.Minute:    "2日1時間5分",
+		59*time.Minute + 40*time.Second: "1時間0分",
+	}
+	for d, want := range tests {
+		assert.Equal(t, want, formatDuration(d), d.String())
+	}
+}
+
+// useTokenProfile 一時ディレクトリの設定ファイルとworkプロファイルを利用する
+func useTokenProfile(t *testing.T, store func(*Config, string) CredentialStore) (dir string, restore func()) {
+	dir, err := ioutil.TempDir("", "aka-cli")
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldCfg, oldPath, oldCred, oldActive := cfg, configPath, credentials, active
+	cfg = &Config{CurrentProfile: "work", Profiles: map[string]*Profile{
+		"work": {CompanyCode: "abc", StaffID: 1},
+	}}
+	configPath = filepath.Join(dir, "config.yaml")
+	credentials = store(cfg, configPath)
+	active = settings{Profile: "work"}
+	return dir, func() {
+		cfg, configPath, credentials, active = oldCfg, oldPath, oldCred, oldActive
+		os.RemoveAll(dir)
+	}
+}
+
+func plainCredentials(c *Config, path string) CredentialStore {
+	return &plainStore{cfg: c, path: path}
+}
+
+func TestSaveToken(t *testing.T) {
+	_, restore := useTokenProfile(t, plainCredentials)
+	defer restore()
+
+	srv := akashitest.NewServer()
+	defer srv.Close()
+	srv.AddToken("abc", "old", 1, time.Time{})
+	defaultClient := akashi.DefaultClient
+	defer func() { akashi.DefaultClient = defaultClient }()
+	akashi.DefaultClient = srv.Client()
+	loginCompanyCode = "abc"
+	defer func() { loginCompanyCode = "" }()
+	ctx := context.Background()
+
+	// token reissue
+	res, err := akashi.PostTokenReissue(ctx, akashi.PostTokenReissueParam{LoginCompanyCode: "abc", Token: "old"})
+	assert.NoError(t, err)
+	assert.NoError(t, saveReissuedToken(res))
+	saved, err := loadConfig(configPath)
+	assert.NoError(t, err)
+	p := saved.Profiles["work"]
+	assert.Equal(t, res.Token, p.Token)
+	if assert.NotNil(t, p.TokenExpiredAt) {
+		assert.True(t, res.ExpiredAt.Equal(*p.TokenExpiredAt))
+	}
+	assert.Equal(t, 1, p.StaffID)
+
+	// token set: 従業員情報を取得できない場合は従業員IDを変更しない
+	exp := time.Date(2026, 12, 31, 23, 59, 59, 0, akashi.Location)
+	assert.NoError(t, setToken(ctx, "unknown", &exp))
+	saved, _ = loadConfig(configPath)
+	p = saved.Profiles["work"]
+	assert.Equal(t, "unknown", p.Token)
+	if assert.NotNil(t, p.TokenExpiredAt) {
+		assert.True(t, exp.Equal(*p.TokenExpiredAt))
+	}
+	assert.Equal(t, 1, p.StaffID)
+
+	// token set: 取得できた従業員IDで更新する
+	srv.AddStaff("abc", akashi.Staff{ID: 2})
+	srv.AddToken("abc", "other", 2, time.Time{})
+	assert.NoError(t, setToken(ctx, "other", nil))
+	saved, _ = loadConfig(configPath)
+	assert.Equal(t, 2, saved.Profiles["work"].StaffID)
+	assert.Nil(t, saved.Profiles["work"].TokenExpiredAt)
+}
+
+// memCredentials メモリ上のCredentialStore
+type memCredentials map[string]Credential
+
+func (m memCredentials) Get(profile string) (Credential, error) {
+	c, ok := m[profile]
+	if !ok {
+		return Credential{}, errCredentialNotFound
+	}
+	return c, nil
+}
+
+func (m memCredentials) Set(profile string, c Credential) error {
+	m[profile] = c
+	return nil
+}
+
+func (m memCredentials) Delete(profile string) error {
+	delete(m, profile)
+	return nil
+}
+
+func (m memCredentials) Profiles() ([]string, error) {
+	return nil, nil
+}
+
+func TestSaveTokenRollback(t *testing.T) {
+	mem := memCredentials{"work": {Token: "old"}}
+	dir, restore := useTokenProfile(t, func(*Config, string) CredentialStore { return mem })
+	defer restore()
+
+	// 設定ファイルのディレクトリを作成できないようにする
+	blocker := filepath.Join(dir, "file")
+	assert.NoError(t, ioutil.WriteFile(blocker, nil, 0600))
+	configPath = filepath.Join(blocker, "config.yaml")
+
+	err := saveToken("xyz", "new", nil, 2)
+	assert.Error(t, err)
+	assert.Equal(t, "old", mem["work"].Token)
+	assert.Equal(t, &Profile{CompanyCode: "abc", StaffID: 1}, cfg.Profiles["work"])
+
+	// 保存されていなかった認証情報とプロファイルは削除する
+	active = settings{Profile: "home"}
+	assert.Error(t, saveToken("", "new", nil, 0))
+	_, err = mem.Get("home")
+	assert.True(t, errors.Is(err, errCredentialNotFound))
+	assert.NotContains(t, cfg.Profiles, "home")
+	assert.Equal(t, "work", cfg.CurrentProfile)
+}
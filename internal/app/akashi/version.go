@@ -14,6 +14,9 @@ var versionCmd = &cobra.Command{
 	Use:   "version",
 	Short: "Print the version number of go-akashi",
 	Long:  `All software has versions. This is go-akashi's`,
+	Annotations: map[string]string{
+		annotationNoCredential: "true",
+	},
 	Run: func(cmd *cobra.Command, args []string) {
 		fmt.Println("go-akashi v0.1.0")
 	},
@@ -0,0 +1,107 @@
+package akashitest
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// FaultKind 注入する障害の種類
+type FaultKind int
+
+const (
+	// FaultServerError 500 Internal Server Errorを返す
+	FaultServerError FaultKind = iota + 1
+	// FaultUnavailable 503 Service Unavailableを返す
+	FaultUnavailable
+	// FaultRateLimited 429 Too Many Requestsを返す
+	FaultRateLimited
+	// FaultFailure 200でsuccess=falseを返す
+	FaultFailure
+	// FaultMalformedJSON 壊れたJSONを返す
+	FaultMalformedJSON
+	// FaultUnauthorized 401 Unauthorizedを返す
+	FaultUnauthorized
+)
+
+// Fault 注入する障害
+type Fault struct {
+	Kind       FaultKind
+	Method     string // 対象のHTTPメソッド(空の場合はすべて)
+	Path       string // 対象のパスの前方一致(空の場合はすべて)
+	Times      int    // 障害を発生させる回数(0以下の場合は無制限)
+	RetryAfter int    // FaultRateLimited, FaultUnavailableで返すRetry-After(秒)
+	Code       string // FaultFailure, FaultUnauthorizedで返すエラーコード
+	Message    string // FaultFailure, FaultUnauthorizedで返すエラーメッセージ
+}
+
+// InjectFault 障害を注入する
+// 複数の障害が一致する場合は先に注入したものが優先される
+func (s *Server) InjectFault(f Fault) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = append(s.faults, &f)
+}
+
+// ClearFaults 注入した障害をすべて取り除く
+func (s *Server) ClearFaults() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.faults = nil
+}
+
+// matchFault リクエストに一致する障害を返し、残り回数を減らす
+func (s *Server) matchFault(r *http.Request) *Fault {
+	for i, f := range s.faults {
+		if f.Method != "" && f.Method != r.Method {
+			continue
+		}
+		if !strings.HasPrefix(r.URL.Path, f.Path) {
+			continue
+		}
+		if f.Times > 0 {
+			f.Times--
+			if f.Times == 0 {
+				s.faults = append(s.faults[:i:i], s.faults[i+1:]...)
+			}
+		}
+		return f
+	}
+	return nil
+}
+
+func (f *Fault) write(w http.ResponseWriter) {
+	if f.RetryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(f.RetryAfter))
+	}
+	switch f.Kind {
+	case FaultUnavailable:
+		writeError(w, http.StatusServiceUnavailable, "SERVICE_UNAVAILABLE", "service unavailable")
+	case FaultRateLimited:
+		writeError(w, http.StatusTooManyRequests, "RATE_LIMITED", "too many requests")
+	case FaultFailure:
+		code, msg := f.Code, f.Message
+		if code == "" {
+			code = "VALIDATION_ERROR"
+		}
+		if msg == "" {
+			msg = "request failed"
+		}
+		writeError(w, http.StatusOK, code, msg)
+	case FaultUnauthorized:
+		code, msg := f.Code, f.Message
+		if code == "" {
+			code = "INVALID_TOKEN"
+		}
+		if msg == "" {
+			msg = "unauthorized"
+		}
+		writeError(w, http.StatusUnauthorized, code, msg)
+	case FaultMalformedJSON:
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{"success":true,"response":`))
+	default:
+		writeError(w, http.StatusInternalServerError, "INTERNAL_SERVER_ERROR", "internal server error")
+	}
+}
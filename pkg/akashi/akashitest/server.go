@@ -0,0 +1,449 @@
+// Package akashitest AKASHI APIを模倣するテスト・オフライン開発用のサーバ
+package akashitest
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"hapoon/go-akashi/pkg/akashi"
+)
+
+// DefaultPageSize 従業員一覧の1ページあたりの件数
+const DefaultPageSize = 100
+
+// Server AKASHI APIを模倣するサーバ
+//
+// 従業員・打刻・アラート・トークンをメモリ上に保持する
+type Server struct {
+	*httptest.Server
+
+	// PageSize 従業員一覧の1ページあたりの件数
+	PageSize int
+	// Now サーバの現在時刻(打刻日時やトークンの有効期限判定に利用する)
+	Now func() time.Time
+	// TokenLifetime 再発行したトークンの有効期間
+	TokenLifetime time.Duration
+
+	mu       sync.Mutex
+	tokens   map[string]*tokenInfo
+	staffs   map[string][]akashi.Staff
+	stamps   map[staffKey][]akashi.Stamp
+	alerts   map[staffKey][]akashi.Alert
+	faults   []*Fault
+	requests []Request
+}
+
+type tokenInfo struct {
+	companyCode string
+	staffID     int
+	expiredAt   time.Time
+}
+
+type staffKey struct {
+	companyCode string
+	staffID     int
+}
+
+// Request サーバが受け付けたリクエスト
+type Request struct {
+	Method string
+	Path   string
+	Query  url.Values
+	Header http.Header
+	Body   []byte
+}
+
+// NewServer サーバを起動する
+// 利用後はCloseを呼び出す
+func NewServer() *Server {
+	s := &Server{
+		PageSize:      DefaultPageSize,
+		Now:           time.Now,
+		TokenLifetime: 30 * 24 * time.Hour,
+		tokens:        map[string]*tokenInfo{},
+		staffs:        map[string][]akashi.Staff{},
+		stamps:        map[staffKey][]akashi.Stamp{},
+		alerts:        map[staffKey][]akashi.Alert{},
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	return s
+}
+
+// Client サーバに接続するクライアントを生成する
+// クライアントの現在時刻はサーバのNowを利用する
+func (s *Server) Client(opts ...akashi.Option) *akashi.Client {
+	base := []akashi.Option{
+		akashi.WithBaseURL(s.URL),
+		akashi.WithClock(func() time.Time { return s.Now() }),
+	}
+	return akashi.NewClient(append(base, opts...)...)
+}
+
+// AddToken アクセストークンを登録する
+// expiredAtがゼロ値の場合は有効期限なし
+func (s *Server) AddToken(companyCode, token string, staffID int, expiredAt time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokens[token] = &tokenInfo{companyCode: companyCode, staffID: staffID, expiredAt: expiredAt}
+}
+
+// AddStaff 従業員を登録する
+func (s *Server) AddStaff(companyCode string, staffs ...akashi.Staff) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.staffs[companyCode] = append(s.staffs[companyCode], staffs...)
+}
+
+// AddStamps 打刻を登録する
+func (s *Server) AddStamps(companyCode string, staffID int, stamps ...akashi.Stamp) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	k := staffKey{companyCode, staffID}
+	s.stamps[k] = append(s.stamps[k], stamps...)
+	sortStamps(s.stamps[k])
+}
+
+// AddAlerts アラートを登録する
+func (s *Server) AddAlerts(companyCode string, staffID int, alerts ...akashi.Alert) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	k := staffKey{companyCode, staffID}
+	s.alerts[k] = append(s.alerts[k], alerts...)
+}
+
+// Stamps 登録されている打刻を返す
+func (s *Server) Stamps(companyCode string, staffID int) []akashi.Stamp {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]akashi.Stamp(nil), s.stamps[staffKey{companyCode, staffID}]...)
+}
+
+// Requests 受け付けたリクエストを古い順に返す
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+// ClearRequests 受け付けたリクエストの記録を消去する
+func (s *Server) ClearRequests() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = nil
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, Request{
+		Method: r.Method,
+		Path:   r.URL.Path,
+		Query:  r.URL.Query(),
+		Header: r.Header.Clone(),
+		Body:   body,
+	})
+	if f := s.matchFault(r); f != nil {
+		f.write(w)
+		return
+	}
+
+	seg := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch {
+	case len(seg) == 3 && seg[0] == "token" && seg[1] == "reissue" && r.Method == http.MethodPost:
+		s.postTokenReissue(w, seg[2], body)
+	case len(seg) >= 2 && len(seg) <= 3 && seg[1] == "staffs" && r.Method == http.MethodGet:
+		s.getStaff(w, r, seg[0], optionalID(seg))
+	case len(seg) >= 2 && len(seg) <= 3 && seg[1] == "stamps" && r.Method == http.MethodGet:
+		s.getStamps(w, r, seg[0], optionalID(seg))
+	case len(seg) == 2 && seg[1] == "stamps" && r.Method == http.MethodPost:
+		s.postStamp(w, seg[0], body)
+	case len(seg) == 2 && seg[1] == "alerts" && r.Method == http.MethodGet:
+		s.getAlerts(w, r, seg[0])
+	default:
+		writeError(w, http.StatusNotFound, "NOT_FOUND", "not found")
+	}
+}
+
+// optionalID パスの3番目の要素を従業員IDとして返す(不正な場合は-1)
+func optionalID(seg []string) int {
+	if len(seg) < 3 {
+		return 0
+	}
+	id, err := strconv.Atoi(seg[2])
+	if err != nil {
+		return -1
+	}
+	return id
+}
+
+// authorize トークンを検証して認証された従業員を返す
+func (s *Server) authorize(w http.ResponseWriter, companyCode, token string) (*tokenInfo, bool) {
+	info, ok := s.tokens[token]
+	if token == "" || !ok {
+		writeError(w, http.StatusUnauthorized, "INVALID_TOKEN", "invalid token")
+		return nil, false
+	}
+	if !info.expiredAt.IsZero() && !s.Now().Before(info.expiredAt) {
+		writeError(w, http.StatusUnauthorized, "TOKEN_EXPIRED", "token expired")
+		return nil, false
+	}
+	if info.companyCode != companyCode {
+		writeError(w, http.StatusForbidden, "PERMISSION_DENIED", "permission denied")
+		return nil, false
+	}
+	return info, true
+}
+
+func (s *Server) findStaff(companyCode string, staffID int) (akashi.Staff, bool) {
+	for _, v := range s.staffs[companyCode] {
+		if v.ID == staffID {
+			return v, true
+		}
+	}
+	return akashi.Staff{}, false
+}
+
+func (s *Server) getStaff(w http.ResponseWriter, r *http.Request, companyCode string, staffID int) {
+	q := r.URL.Query()
+	info, ok := s.authorize(w, companyCode, q.Get("token"))
+	if !ok {
+		return
+	}
+	if staffID < 0 {
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "invalid staff id")
+		return
+	}
+	all := s.staffs[companyCode]
+	res := akashi.GetStaffResponse{LoginCompanyCode: companyCode, TotalCount: len(all), Staffs: []akashi.Staff{}}
+	switch {
+	case staffID != 0:
+		v, ok := s.findStaff(companyCode, staffID)
+		if !ok {
+			writeError(w, http.StatusNotFound, "NOT_FOUND", "staff not found")
+			return
+		}
+		res.Staffs = append(res.Staffs, v)
+	case q.Get("target") != "":
+		t, ok := s.tokens[q.Get("target")]
+		if !ok || t.companyCode != companyCode {
+			writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "invalid target")
+			return
+		}
+		if v, ok := s.findStaff(companyCode, t.staffID); ok {
+			res.Staffs = append(res.Staffs, v)
+		}
+	case q.Get("page") != "":
+		page, err := strconv.Atoi(q.Get("page"))
+		if err != nil || page < 1 {
+			writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "invalid page")
+			return
+		}
+		size := s.PageSize
+		if size <= 0 {
+			size = DefaultPageSize
+		}
+		for i := (page - 1) * size; i < page*size && i < len(all); i++ {
+			res.Staffs = append(res.Staffs, all[i])
+		}
+	default:
+		if v, ok := s.findStaff(companyCode, info.staffID); ok {
+			res.Staffs = append(res.Staffs, v)
+		}
+	}
+	res.Count = len(res.Staffs)
+	writeResponse(w, res)
+}
+
+func (s *Server) getStamps(w http.ResponseWriter, r *http.Request, companyCode string, staffID int) {
+	q := r.URL.Query()
+	info, ok := s.authorize(w, companyCode, q.Get("token"))
+	if !ok {
+		return
+	}
+	if staffID < 0 {
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "invalid staff id")
+		return
+	}
+	if staffID == 0 {
+		staffID = info.staffID
+	}
+	start, err1 := time.ParseInLocation(akashi.DateFormat, q.Get("start_date"), akashi.Location)
+	end, err2 := time.ParseInLocation(akashi.DateFormat, q.Get("end_date"), akashi.Location)
+	if err1 != nil || err2 != nil || end.Before(start) {
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "invalid start_date or end_date")
+		return
+	}
+	res := akashi.GetStampResponse{LoginCompanyCode: companyCode, StaffID: staffID, Stamps: []akashi.Stamp{}}
+	for _, v := range s.stamps[staffKey{companyCode, staffID}] {
+		if v.StampedAt == nil || v.StampedAt.Before(start) || v.StampedAt.After(end) {
+			continue
+		}
+		res.Stamps = append(res.Stamps, v)
+	}
+	res.Count = len(res.Stamps)
+	writeResponse(w, res)
+}
+
+func (s *Server) postStamp(w http.ResponseWriter, companyCode string, body []byte) {
+	var p struct {
+		Token     string           `json:"token"`
+		Type      akashi.StampType `json:"type"`
+		StampedAt string           `json:"stampedAt"`
+		Timezone  string           `json:"timezone"`
+	}
+	if err := json.Unmarshal(body, &p); err != nil {
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "invalid body")
+		return
+	}
+	// クライアントでの打刻日時はタイムゾーンの壁時計時刻
+	var stampedAt *akashi.AkTime
+	if p.StampedAt != "" {
+		loc := akashi.Location
+		if p.Timezone != "" {
+			tz, err := akashi.ParseTimezone(p.Timezone)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "invalid timezone")
+				return
+			}
+			loc = tz
+		}
+		t, err := time.ParseInLocation(akashi.ReturnDateFormat, p.StampedAt, loc)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "invalid stampedAt")
+			return
+		}
+		stampedAt = akashi.NewAkTime(t)
+	}
+	info, ok := s.authorize(w, companyCode, p.Token)
+	if !ok {
+		return
+	}
+	k := staffKey{companyCode, info.staffID}
+	if p.Type == akashi.StampTypeUnknown {
+		p.Type = nextStampType(s.stamps[k])
+	}
+	if p.Type.String() == "" {
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "invalid type")
+		return
+	}
+	// AKASHIと同じく打刻日時はサーバの時刻、クライアントでの打刻日時はローカル打刻時刻として記録する
+	now := akashi.NewAkTime(s.Now().In(akashi.Location).Truncate(time.Second))
+	stamp := akashi.Stamp{
+		StampedAt: now,
+		Type:      p.Type,
+		LocalTime: stampedAt,
+		Timezone:  p.Timezone,
+	}
+	s.stamps[k] = append(s.stamps[k], stamp)
+	sortStamps(s.stamps[k])
+	writeResponse(w, akashi.PostStampResponse{
+		LoginCompanyCode: companyCode,
+		StaffID:          info.staffID,
+		Type:             stamp.Type,
+		StampedAt:        stamp.StampedAt,
+	})
+}
+
+// nextStampType 打刻種別の指定がない場合に記録する打刻種別
+func nextStampType(stamps []akashi.Stamp) akashi.StampType {
+	if len(stamps) == 0 {
+		return akashi.StampTypeGoToWork
+	}
+	switch stamps[len(stamps)-1].Type {
+	case akashi.StampTypeGoToWork, akashi.StampTypeGoStraight, akashi.StampTypeBreakReturn:
+		return akashi.StampTypeLeaveWork
+	case akashi.StampTypeBreak:
+		return akashi.StampTypeBreakReturn
+	default:
+		return akashi.StampTypeGoToWork
+	}
+}
+
+func (s *Server) getAlerts(w http.ResponseWriter, r *http.Request, companyCode string) {
+	info, ok := s.authorize(w, companyCode, r.URL.Query().Get("token"))
+	if !ok {
+		return
+	}
+	alerts := append([]akashi.Alert{}, s.alerts[staffKey{companyCode, info.staffID}]...)
+	writeResponse(w, akashi.GetAlertResponse{
+		LoginCompanyCode: companyCode,
+		StaffID:          info.staffID,
+		Count:            len(alerts),
+		Alerts:           alerts,
+	})
+}
+
+func (s *Server) postTokenReissue(w http.ResponseWriter, companyCode string, body []byte) {
+	var p struct {
+		Token string `json:"token"`
+	}
+	if err := json.Unmarshal(body, &p); err != nil {
+		writeError(w, http.StatusBadRequest, "VALIDATION_ERROR", "invalid body")
+		return
+	}
+	info, ok := s.authorize(w, companyCode, p.Token)
+	if !ok {
+		return
+	}
+	delete(s.tokens, p.Token)
+	token := newToken()
+	expiredAt := s.Now().In(akashi.Location).Add(s.TokenLifetime).Truncate(time.Second)
+	s.tokens[token] = &tokenInfo{companyCode: companyCode, staffID: info.staffID, expiredAt: expiredAt}
+	writeResponse(w, akashi.PostTokenReissueResponse{
+		LoginCompanyCode: companyCode,
+		StaffID:          info.staffID,
+		Token:            token,
+		ExpiredAt:        akashi.NewAkTime(expiredAt),
+	})
+}
+
+func newToken() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+func sortStamps(stamps []akashi.Stamp) {
+	sort.SliceStable(stamps, func(i, j int) bool {
+		if stamps[i].StampedAt == nil || stamps[j].StampedAt == nil {
+			return stamps[j].StampedAt != nil
+		}
+		return stamps[i].StampedAt.Before(stamps[j].StampedAt.Time)
+	})
+}
+
+type envelope struct {
+	Success  bool           `json:"success"`
+	Response interface{}    `json:"response,omitempty"`
+	Errors   []akashi.Error `json:"errors,omitempty"`
+}
+
+func writeResponse(w http.ResponseWriter, v interface{}) {
+	writeJSON(w, http.StatusOK, envelope{Success: true, Response: v})
+}
+
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	writeJSON(w, status, envelope{Errors: []akashi.Error{{Code: code, Message: message}}})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -2,11 +2,9 @@ package akashi
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
-	"net/url"
 )
 
 // Alert アラート情報
@@ -84,39 +82,25 @@ type GetAlertResponse struct {
 }
 
 // GetAlerts アラート情報取得
+// DefaultClientを利用する
 func GetAlerts(ctx context.Context, param GetAlertParam) (GetAlertResponse, error) {
+	return DefaultClient.GetAlerts(ctx, param)
+}
+
+// GetAlerts アラート情報取得
+func (c *Client) GetAlerts(ctx context.Context, param GetAlertParam) (GetAlertResponse, error) {
 	if param.LoginCompanyCode == "" {
 		return GetAlertResponse{}, errors.New("LoginCompanyCode must be set")
 	}
-	if param.Token == "" {
+	if param.Token == "" && c.tokens == nil {
 		return GetAlertResponse{}, errors.New("Token must be set")
 	}
-	endpoint := fmt.Sprintf("/%s/alerts", param.LoginCompanyCode)
-	uv := url.Values{}
-	uv.Add("token", param.Token)
-	q := uv.Encode()
-	if q != "" {
-		endpoint += "?" + q
-	}
+	path := fmt.Sprintf("/%s/alerts", param.LoginCompanyCode)
 
-	cli := NewClient(param.LoginCompanyCode, param.Token)
-	res, err := cli.Get(ctx, endpoint)
-	if err != nil {
+	var res GetAlertResponse
+	r := &request{method: http.MethodGet, path: path, token: param.Token}
+	if err := c.call(ctx, r, &res); err != nil {
 		return GetAlertResponse{}, err
 	}
-	if res.StatusCode != http.StatusOK {
-		return GetAlertResponse{}, fmt.Errorf("Status code=%d", res.StatusCode)
-	}
-	var gar struct {
-		Success  bool             `json:"success"`
-		Response GetAlertResponse `json:"response"`
-		Errors   []Error          `json:"errors"`
-	}
-	if err := json.NewDecoder(res.Body).Decode(&gar); err != nil {
-		return GetAlertResponse{}, err
-	}
-	if !gar.Success {
-		return GetAlertResponse{}, errors.New("Requesting Alert API failed")
-	}
-	return gar.Response, nil
+	return res, nil
 }
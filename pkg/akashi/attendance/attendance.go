@@ -0,0 +1,317 @@
+// Package attendance 打刻データを勤務日ごとの勤怠記録にまとめる
+package attendance
+
+import (
+	"sort"
+	"time"
+
+	"hapoon/go-akashi/pkg/akashi"
+)
+
+// IssueKind 打刻の不備の種類
+type IssueKind int
+
+const (
+	// IssueMissingStart 出勤・直行の打刻がない退勤・直帰
+	IssueMissingStart IssueKind = iota + 1
+	// IssueMissingEnd 退勤・直帰の打刻がない出勤・直行
+	IssueMissingEnd
+	// IssueMissingBreakStart 休憩入の打刻がない休憩戻
+	IssueMissingBreakStart
+	// IssueMissingBreakEnd 休憩戻の打刻がない休憩入
+	IssueMissingBreakEnd
+	// IssueOutOfOrder 順序の誤った打刻(勤務外の休憩、出勤の重複など)
+	IssueOutOfOrder
+	// IssueInvalidStamp 打刻日時のない打刻や不明な打刻種別
+	IssueInvalidStamp
+)
+
+func (k IssueKind) String() string {
+	switch k {
+	case IssueMissingStart:
+		return "出勤打刻なし"
+	case IssueMissingEnd:
+		return "退勤打刻なし"
+	case IssueMissingBreakStart:
+		return "休憩入打刻なし"
+	case IssueMissingBreakEnd:
+		return "休憩戻打刻なし"
+	case IssueOutOfOrder:
+		return "打刻順序不正"
+	case IssueInvalidStamp:
+		return "不正な打刻"
+	default:
+		return ""
+	}
+}
+
+// Issue 打刻の不備
+type Issue struct {
+	Kind IssueKind        // 不備の種類
+	At   time.Time        // 不備のある打刻の日時(打刻がない場合は対応する打刻の日時)
+	Type akashi.StampType // 不備のある打刻の種別
+}
+
+// Session 出勤・直行から退勤・直帰までの勤務
+// 打刻がない側の日時はゼロ値になる
+type Session struct {
+	Start     time.Time        // 出勤日時
+	End       time.Time        // 退勤日時
+	StartType akashi.StampType // 出勤・直行
+	EndType   akashi.StampType // 退勤・直帰
+}
+
+// Complete 出勤と退勤の両方が揃っているか
+func (s Session) Complete() bool {
+	return !s.Start.IsZero() && !s.End.IsZero()
+}
+
+// Duration 勤務時間。揃っていない場合は0
+func (s Session) Duration() time.Duration {
+	if !s.Complete() {
+		return 0
+	}
+	return s.End.Sub(s.Start)
+}
+
+// Break 休憩入から休憩戻までの休憩
+// 打刻がない側の日時はゼロ値になる
+type Break struct {
+	Start time.Time // 休憩入日時
+	End   time.Time // 休憩戻日時
+}
+
+// Complete 休憩入と休憩戻の両方が揃っているか
+func (b Break) Complete() bool {
+	return !b.Start.IsZero() && !b.End.IsZero()
+}
+
+// Duration 休憩時間。揃っていない場合は0
+func (b Break) Duration() time.Duration {
+	if !b.Complete() {
+		return 0
+	}
+	return b.End.Sub(b.Start)
+}
+
+// Day 従業員の勤務日ごとの勤怠記録
+type Day struct {
+	StaffID  int            // 従業員ID
+	Date     time.Time      // 勤務日(Locationでの0時)
+	Stamps   []akashi.Stamp // 勤務日の打刻(打刻日時順)
+	Sessions []Session      // 勤務
+	Breaks   []Break        // 休憩
+	Gross    time.Duration  // 総勤務時間(揃っている勤務の合計)
+	Break    time.Duration  // 休憩時間(揃っている勤務に含まれる揃っている休憩の合計)
+	Net      time.Duration  // 実労働時間(総勤務時間 - 休憩時間)
+	Issues   []Issue        // 打刻の不備
+}
+
+// FirstIn 最初の出勤日時。ない場合はゼロ値
+func (d Day) FirstIn() time.Time {
+	for _, s := range d.Sessions {
+		if !s.Start.IsZero() {
+			return s.Start
+		}
+	}
+	return time.Time{}
+}
+
+// LastOut 最後の退勤日時。ない場合はゼロ値
+func (d Day) LastOut() time.Time {
+	for i := len(d.Sessions) - 1; i >= 0; i-- {
+		if !d.Sessions[i].End.IsZero() {
+			return d.Sessions[i].End
+		}
+	}
+	return time.Time{}
+}
+
+// Builder 打刻データから勤怠記録を作成する
+type Builder struct {
+	// DayChangeHour 日付変更時刻(0〜23)
+	// この時刻より前の打刻は前日の勤務として扱うので、日をまたぐ勤務は退勤が収まる時刻を指定する
+	DayChangeHour int
+	// Location 勤務日を判定するタイムゾーン。nilの場合はakashi.Location
+	Location *time.Location
+}
+
+// BuildResponse GetStampsの結果から勤怠記録を作成する
+func (b Builder) BuildResponse(res akashi.GetStampResponse) []Day {
+	return b.Build(res.StaffID, res.Stamps)
+}
+
+// BuildAll 従業員ごとの打刻データから勤怠記録を作成する
+// 結果は従業員ID、勤務日の順に並ぶ
+func (b Builder) BuildAll(stamps map[int][]akashi.Stamp) []Day {
+	ids := make([]int, 0, len(stamps))
+	for id := range stamps {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	var days []Day
+	for _, id := range ids {
+		days = append(days, b.Build(id, stamps[id])...)
+	}
+	return days
+}
+
+// Build 1人の従業員の打刻データから勤務日ごとの勤怠記録を作成する
+// 打刻はサーバが受け付けた日時ではなく打刻した時刻(Stamp.ClientTime)で並べて勤務日に振り分ける。
+// 結果は勤務日順に並ぶ。打刻日時のない打刻は最初の勤務日の不備として扱う
+func (b Builder) Build(staffID int, stamps []akashi.Stamp) []Day {
+	var valid, invalid []akashi.Stamp
+	for _, s := range stamps {
+		if s.ClientTime().IsZero() {
+			invalid = append(invalid, s)
+			continue
+		}
+		valid = append(valid, s)
+	}
+	sort.SliceStable(valid, func(i, j int) bool {
+		return valid[i].ClientTime().Before(valid[j].ClientTime())
+	})
+
+	var days []Day
+	for _, s := range valid {
+		date := b.BusinessDay(s.ClientTime())
+		if len(days) == 0 || !days[len(days)-1].Date.Equal(date) {
+			days = append(days, Day{StaffID: staffID, Date: date})
+		}
+		d := &days[len(days)-1]
+		d.Stamps = append(d.Stamps, s)
+	}
+	for i := range days {
+		pair(&days[i])
+	}
+	if len(invalid) > 0 {
+		if len(days) == 0 {
+			days = append(days, Day{StaffID: staffID})
+		}
+		for _, s := range invalid {
+			days[0].Issues = append(days[0].Issues, Issue{Kind: IssueInvalidStamp, Type: s.Type})
+		}
+	}
+	return days
+}
+
+// BusinessDay tの勤務日
+func (b Builder) BusinessDay(t time.Time) time.Time {
+	loc := b.Location
+	if loc == nil {
+		loc = akashi.Location
+	}
+	t = t.In(loc).Add(-time.Duration(b.DayChangeHour) * time.Hour)
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+}
+
+// pair 打刻した時刻順の打刻を勤務と休憩の組にして時間を集計する
+func pair(d *Day) {
+	var (
+		session *Session
+		brk     *Break
+	)
+	issue := func(kind IssueKind, at time.Time, typ akashi.StampType) {
+		d.Issues = append(d.Issues, Issue{Kind: kind, At: at, Type: typ})
+	}
+	closeBreak := func() {
+		if brk == nil {
+			return
+		}
+		d.Breaks = append(d.Breaks, *brk)
+		brk = nil
+	}
+	closeSession := func() {
+		if session == nil {
+			return
+		}
+		d.Sessions = append(d.Sessions, *session)
+		session = nil
+	}
+
+	for _, s := range d.Stamps {
+		t := s.ClientTime()
+		switch s.Type {
+		case akashi.StampTypeGoToWork, akashi.StampTypeGoStraight:
+			if session != nil {
+				// 退勤せずに再度出勤している
+				issue(IssueOutOfOrder, t, s.Type)
+				if brk != nil {
+					issue(IssueMissingBreakEnd, brk.Start, akashi.StampTypeBreak)
+					closeBreak()
+				}
+				issue(IssueMissingEnd, session.Start, session.StartType)
+				closeSession()
+			}
+			session = &Session{Start: t, StartType: s.Type}
+		case akashi.StampTypeLeaveWork, akashi.StampTypeBounce:
+			if brk != nil {
+				issue(IssueMissingBreakEnd, brk.Start, akashi.StampTypeBreak)
+				closeBreak()
+			}
+			if session == nil {
+				issue(IssueMissingStart, t, s.Type)
+				session = &Session{}
+			}
+			session.End = t
+			session.EndType = s.Type
+			closeSession()
+		case akashi.StampTypeBreak:
+			if session == nil {
+				issue(IssueOutOfOrder, t, s.Type)
+				continue
+			}
+			if brk != nil {
+				// 休憩戻の前に再度休憩入している
+				issue(IssueOutOfOrder, t, s.Type)
+				continue
+			}
+			brk = &Break{Start: t}
+		case akashi.StampTypeBreakReturn:
+			if brk == nil {
+				if session == nil {
+					issue(IssueOutOfOrder, t, s.Type)
+				} else {
+					issue(IssueMissingBreakStart, t, s.Type)
+					d.Breaks = append(d.Breaks, Break{End: t})
+				}
+				continue
+			}
+			brk.End = t
+			closeBreak()
+		default:
+			issue(IssueInvalidStamp, t, s.Type)
+		}
+	}
+	if brk != nil {
+		issue(IssueMissingBreakEnd, brk.Start, akashi.StampTypeBreak)
+		closeBreak()
+	}
+	if session != nil {
+		issue(IssueMissingEnd, session.Start, session.StartType)
+		closeSession()
+	}
+
+	for _, s := range d.Sessions {
+		d.Gross += s.Duration()
+	}
+	for _, b := range d.Breaks {
+		if within(b, d.Sessions) {
+			d.Break += b.Duration()
+		}
+	}
+	d.Net = d.Gross - d.Break
+}
+
+// within 休憩が揃っている勤務のいずれかに含まれるか
+func within(b Break, sessions []Session) bool {
+	if !b.Complete() {
+		return false
+	}
+	for _, s := range sessions {
+		if s.Complete() && !b.Start.Before(s.Start) && !b.End.After(s.End) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,187 @@
+package attendance_test
+
+import (
+	"testing"
+	"time"
+
+	"hapoon/go-akashi/pkg/akashi"
+	"hapoon/go-akashi/pkg/akashi/attendance"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var jst = time.FixedZone("JST", 9*60*60)
+
+func stamp(typ akashi.StampType, day, hour, min int) akashi.Stamp {
+	return akashi.Stamp{Type: typ, StampedAt: akashi.NewAkTime(time.Date(2026, 9, day, hour, min, 0, 0, jst))}
+}
+
+func kinds(issues []attendance.Issue) []attendance.IssueKind {
+	var ks []attendance.IssueKind
+	for _, i := range issues {
+		ks = append(ks, i.Kind)
+	}
+	return ks
+}
+
+func TestBuild(t *testing.T) {
+	b := attendance.Builder{Location: jst}
+	// 順不同で渡しても打刻日時順に処理する
+	days := b.Build(1, []akashi.Stamp{
+		stamp(akashi.StampTypeLeaveWork, 1, 18, 30),
+		stamp(akashi.StampTypeBreakReturn, 1, 13, 0),
+		stamp(akashi.StampTypeGoToWork, 1, 9, 0),
+		stamp(akashi.StampTypeBreak, 1, 12, 0),
+		stamp(akashi.StampTypeGoStraight, 2, 8, 0),
+		stamp(akashi.StampTypeBounce, 2, 17, 0),
+	})
+	if !assert.Len(t, days, 2) {
+		return
+	}
+	d := days[0]
+	assert.Equal(t, 1, d.StaffID)
+	assert.True(t, d.Date.Equal(time.Date(2026, 9, 1, 0, 0, 0, 0, jst)))
+	assert.Equal(t, 9*time.Hour+30*time.Minute, d.Gross)
+	assert.Equal(t, time.Hour, d.Break)
+	assert.Equal(t, 8*time.Hour+30*time.Minute, d.Net)
+	assert.Empty(t, d.Issues)
+	assert.True(t, d.FirstIn().Equal(time.Date(2026, 9, 1, 9, 0, 0, 0, jst)))
+	assert.True(t, d.LastOut().Equal(time.Date(2026, 9, 1, 18, 30, 0, 0, jst)))
+
+	d = days[1]
+	assert.Equal(t, akashi.StampTypeGoStraight, d.Sessions[0].StartType)
+	assert.Equal(t, akashi.StampTypeBounce, d.Sessions[0].EndType)
+	assert.Equal(t, 9*time.Hour, d.Net)
+}
+
+func TestBuildCrossMidnight(t *testing.T) {
+	stamps := []akashi.Stamp{
+		stamp(akashi.StampTypeGoToWork, 1, 22, 0),
+		stamp(akashi.StampTypeBreak, 2, 1, 0),
+		stamp(akashi.StampTypeBreakReturn, 2, 1, 45),
+		stamp(akashi.StampTypeLeaveWork, 2, 6, 0),
+	}
+
+	// 0時で日付を変更すると勤務が分かれる
+	days := attendance.Builder{Location: jst}.Build(1, stamps)
+	if assert.Len(t, days, 2) {
+		assert.Equal(t, []attendance.IssueKind{attendance.IssueMissingEnd}, kinds(days[0].Issues))
+		assert.Equal(t, []attendance.IssueKind{attendance.IssueOutOfOrder, attendance.IssueOutOfOrder, attendance.IssueMissingStart}, kinds(days[1].Issues))
+	}
+
+	days = attendance.Builder{Location: jst, DayChangeHour: 7}.Build(1, stamps)
+	if assert.Len(t, days, 1) {
+		d := days[0]
+		assert.True(t, d.Date.Equal(time.Date(2026, 9, 1, 0, 0, 0, 0, jst)))
+		assert.Equal(t, 8*time.Hour, d.Gross)
+		assert.Equal(t, 45*time.Minute, d.Break)
+		assert.Equal(t, 7*time.Hour+15*time.Minute, d.Net)
+		assert.Empty(t, d.Issues)
+	}
+}
+
+func TestBuildBackdated(t *testing.T) {
+	// 9/1 18:30の退勤を9/2 9:00に事後に打刻した
+	backdated := stamp(akashi.StampTypeLeaveWork, 2, 9, 0)
+	backdated.LocalTime = akashi.NewAkTime(time.Date(2026, 9, 1, 18, 30, 0, 0, jst))
+	days := attendance.Builder{Location: jst}.Build(1, []akashi.Stamp{
+		stamp(akashi.StampTypeGoToWork, 1, 9, 0),
+		stamp(akashi.StampTypeGoToWork, 2, 8, 55),
+		backdated,
+	})
+	if !assert.Len(t, days, 2) {
+		return
+	}
+	d := days[0]
+	assert.True(t, d.Date.Equal(time.Date(2026, 9, 1, 0, 0, 0, 0, jst)))
+	assert.Equal(t, 9*time.Hour+30*time.Minute, d.Gross)
+	assert.Empty(t, d.Issues)
+	assert.Equal(t, attendance.StateFinished, d.State())
+
+	d = days[1]
+	assert.Len(t, d.Stamps, 1)
+	assert.Equal(t, attendance.StateWorking, d.State())
+}
+
+func TestBuildIssues(t *testing.T) {
+	b := attendance.Builder{Location: jst}
+	tests := map[string]struct {
+		stamps []akashi.Stamp
+		issues []attendance.IssueKind
+		net    time.Duration
+	}{
+		"missing leave": {
+			stamps: []akashi.Stamp{stamp(akashi.StampTypeGoToWork, 1, 9, 0)},
+			issues: []attendance.IssueKind{attendance.IssueMissingEnd},
+		},
+		"missing go to work": {
+			stamps: []akashi.Stamp{stamp(akashi.StampTypeLeaveWork, 1, 18, 0)},
+			issues: []attendance.IssueKind{attendance.IssueMissingStart},
+		},
+		"missing break return": {
+			stamps: []akashi.Stamp{
+				stamp(akashi.StampTypeGoToWork, 1, 9, 0),
+				stamp(akashi.StampTypeBreak, 1, 12, 0),
+				stamp(akashi.StampTypeLeaveWork, 1, 18, 0),
+			},
+			issues: []attendance.IssueKind{attendance.IssueMissingBreakEnd},
+			net:    9 * time.Hour,
+		},
+		"missing break": {
+			stamps: []akashi.Stamp{
+				stamp(akashi.StampTypeGoToWork, 1, 9, 0),
+				stamp(akashi.StampTypeBreakReturn, 1, 13, 0),
+				stamp(akashi.StampTypeLeaveWork, 1, 18, 0),
+			},
+			issues: []attendance.IssueKind{attendance.IssueMissingBreakStart},
+			net:    9 * time.Hour,
+		},
+		"break before work": {
+			stamps: []akashi.Stamp{
+				stamp(akashi.StampTypeBreak, 1, 8, 0),
+				stamp(akashi.StampTypeGoToWork, 1, 9, 0),
+				stamp(akashi.StampTypeLeaveWork, 1, 18, 0),
+			},
+			issues: []attendance.IssueKind{attendance.IssueOutOfOrder},
+			net:    9 * time.Hour,
+		},
+		"double go to work": {
+			stamps: []akashi.Stamp{
+				stamp(akashi.StampTypeGoToWork, 1, 9, 0),
+				stamp(akashi.StampTypeGoToWork, 1, 10, 0),
+				stamp(akashi.StampTypeLeaveWork, 1, 18, 0),
+			},
+			issues: []attendance.IssueKind{attendance.IssueOutOfOrder, attendance.IssueMissingEnd},
+			net:    8 * time.Hour,
+		},
+		"invalid stamps": {
+			stamps: []akashi.Stamp{
+				{Type: akashi.StampTypeGoToWork},
+				stamp(akashi.StampTypeUnknown, 1, 9, 0),
+			},
+			issues: []attendance.IssueKind{attendance.IssueInvalidStamp, attendance.IssueInvalidStamp},
+		},
+	}
+	for scenario, test := range tests {
+		days := b.Build(1, test.stamps)
+		if !assert.Len(t, days, 1, scenario) {
+			continue
+		}
+		assert.ElementsMatch(t, test.issues, kinds(days[0].Issues), scenario)
+		assert.Equal(t, test.net, days[0].Net, scenario)
+	}
+}
+
+func TestBuildAll(t *testing.T) {
+	days := attendance.Builder{Location: jst}.BuildAll(map[int][]akashi.Stamp{
+		2: {stamp(akashi.StampTypeGoToWork, 1, 9, 0), stamp(akashi.StampTypeLeaveWork, 1, 18, 0)},
+		1: {stamp(akashi.StampTypeGoToWork, 2, 9, 0), stamp(akashi.StampTypeGoToWork, 1, 9, 0)},
+	})
+	if assert.Len(t, days, 3) {
+		assert.Equal(t, 1, days[0].StaffID)
+		assert.Equal(t, 1, days[0].Date.Day())
+		assert.Equal(t, 1, days[1].StaffID)
+		assert.Equal(t, 2, days[1].Date.Day())
+		assert.Equal(t, 2, days[2].StaffID)
+	}
+}
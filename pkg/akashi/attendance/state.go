@@ -0,0 +1,120 @@
+package attendance
+
+import (
+	"fmt"
+	"sort"
+
+	"hapoon/go-akashi/pkg/akashi"
+)
+
+// State 勤務の状態
+type State int
+
+const (
+	// StateNotStarted 未出勤
+	StateNotStarted State = iota
+	// StateWorking 勤務中
+	StateWorking
+	// StateOnBreak 休憩中
+	StateOnBreak
+	// StateFinished 退勤済
+	StateFinished
+)
+
+func (s State) String() string {
+	switch s {
+	case StateNotStarted:
+		return "未出勤"
+	case StateWorking:
+		return "勤務中"
+	case StateOnBreak:
+		return "休憩中"
+	case StateFinished:
+		return "退勤済"
+	default:
+		return ""
+	}
+}
+
+// StateOf 打刻の後の勤務の状態
+// 打刻は打刻した時刻(Stamp.ClientTime)の順に適用する。打刻日時のない打刻と不明な打刻種別は無視する
+func StateOf(stamps []akashi.Stamp) State {
+	sorted := make([]akashi.Stamp, 0, len(stamps))
+	for _, s := range stamps {
+		if !s.ClientTime().IsZero() {
+			sorted = append(sorted, s)
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].ClientTime().Before(sorted[j].ClientTime())
+	})
+
+	state := StateNotStarted
+	for _, s := range sorted {
+		switch s.Type {
+		case akashi.StampTypeGoToWork, akashi.StampTypeGoStraight, akashi.StampTypeBreakReturn:
+			state = StateWorking
+		case akashi.StampTypeLeaveWork, akashi.StampTypeBounce:
+			state = StateFinished
+		case akashi.StampTypeBreak:
+			state = StateOnBreak
+		}
+	}
+	return state
+}
+
+// State 勤務日の最後の打刻の後の状態
+func (d Day) State() State {
+	return StateOf(d.Stamps)
+}
+
+// Next 状態に合わせた打刻の種別
+// 未出勤・退勤済は出勤、勤務中は退勤、休憩中は休憩戻
+func (s State) Next() akashi.StampType {
+	switch s {
+	case StateWorking:
+		return akashi.StampTypeLeaveWork
+	case StateOnBreak:
+		return akashi.StampTypeBreakReturn
+	default:
+		return akashi.StampTypeGoToWork
+	}
+}
+
+// Apply 状態にtypの打刻をした後の状態を返す
+// 状態に合わない打刻の場合は*TransitionError
+func (s State) Apply(typ akashi.StampType) (State, error) {
+	var (
+		from []State
+		to   State
+	)
+	switch typ {
+	case akashi.StampTypeGoToWork, akashi.StampTypeGoStraight:
+		from, to = []State{StateNotStarted, StateFinished}, StateWorking
+	case akashi.StampTypeLeaveWork, akashi.StampTypeBounce:
+		from, to = []State{StateWorking}, StateFinished
+	case akashi.StampTypeBreak:
+		from, to = []State{StateWorking}, StateOnBreak
+	case akashi.StampTypeBreakReturn:
+		from, to = []State{StateOnBreak}, StateWorking
+	}
+	for _, f := range from {
+		if s == f {
+			return to, nil
+		}
+	}
+	return s, &TransitionError{State: s, Type: typ}
+}
+
+// TransitionError 状態に合わない打刻
+type TransitionError struct {
+	State State            // 打刻前の状態
+	Type  akashi.StampType // 打刻の種別
+}
+
+func (e *TransitionError) Error() string {
+	if e.Type.String() == "" {
+		return fmt.Sprintf("unknown stamp type %d", int(e.Type))
+	}
+	return fmt.Sprintf("%sのため%sは打刻できません", e.State, e.Type)
+}
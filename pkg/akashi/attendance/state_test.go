@@ -0,0 +1,63 @@
+package attendance_test
+
+import (
+	"testing"
+
+	"hapoon/go-akashi/pkg/akashi"
+	"hapoon/go-akashi/pkg/akashi/attendance"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStateOf(t *testing.T) {
+	assert.Equal(t, attendance.StateNotStarted, attendance.StateOf(nil))
+	assert.Equal(t, attendance.StateWorking, attendance.StateOf([]akashi.Stamp{
+		stamp(akashi.StampTypeGoToWork, 1, 9, 0),
+	}))
+	// 順不同で渡しても打刻日時順に判定する
+	assert.Equal(t, attendance.StateOnBreak, attendance.StateOf([]akashi.Stamp{
+		stamp(akashi.StampTypeBreak, 1, 12, 0),
+		stamp(akashi.StampTypeGoToWork, 1, 9, 0),
+		{Type: akashi.StampTypeLeaveWork},
+	}))
+	days := attendance.Builder{Location: jst}.Build(1, []akashi.Stamp{
+		stamp(akashi.StampTypeGoStraight, 1, 9, 0),
+		stamp(akashi.StampTypeBounce, 1, 18, 0),
+	})
+	assert.Equal(t, attendance.StateFinished, days[0].State())
+}
+
+func TestStateApply(t *testing.T) {
+	tests := []struct {
+		state attendance.State
+		typ   akashi.StampType
+		want  attendance.State
+		ok    bool
+	}{
+		{attendance.StateNotStarted, akashi.StampTypeGoToWork, attendance.StateWorking, true},
+		{attendance.StateFinished, akashi.StampTypeGoStraight, attendance.StateWorking, true},
+		{attendance.StateWorking, akashi.StampTypeGoToWork, attendance.StateWorking, false},
+		{attendance.StateWorking, akashi.StampTypeBreak, attendance.StateOnBreak, true},
+		{attendance.StateOnBreak, akashi.StampTypeBreakReturn, attendance.StateWorking, true},
+		{attendance.StateWorking, akashi.StampTypeBreakReturn, attendance.StateWorking, false},
+		{attendance.StateOnBreak, akashi.StampTypeLeaveWork, attendance.StateOnBreak, false},
+		{attendance.StateWorking, akashi.StampTypeLeaveWork, attendance.StateFinished, true},
+		{attendance.StateNotStarted, akashi.StampTypeBounce, attendance.StateNotStarted, false},
+	}
+	for _, tt := range tests {
+		got, err := tt.state.Apply(tt.typ)
+		assert.Equal(t, tt.want, got, "%s %s", tt.state, tt.typ)
+		assert.Equal(t, tt.ok, err == nil, "%s %s", tt.state, tt.typ)
+	}
+
+	_, err := attendance.StateWorking.Apply(akashi.StampTypeBreakReturn)
+	assert.EqualError(t, err, "勤務中のため休憩戻は打刻できません")
+}
+
+func TestStateNext(t *testing.T) {
+	for _, s := range []attendance.State{attendance.StateNotStarted, attendance.StateWorking, attendance.StateOnBreak, attendance.StateFinished} {
+		_, err := s.Apply(s.Next())
+		assert.NoError(t, err, s.String())
+	}
+	assert.Equal(t, akashi.StampTypeBreakReturn, attendance.StateOnBreak.Next())
+}
@@ -0,0 +1,197 @@
+// Package calendar 祝日と会社の休業日から営業日を判定する
+package calendar
+
+import (
+	"sync"
+	"time"
+
+	"hapoon/go-akashi/pkg/akashi"
+)
+
+// dateKey 暦日
+type dateKey struct {
+	year  int
+	month time.Month
+	day   int
+}
+
+func keyOf(t time.Time) dateKey {
+	y, m, d := t.Date()
+	return dateKey{y, m, d}
+}
+
+// Calendar 営業日のカレンダー
+//
+// 休業日・出勤日の指定、国民の祝日、週休日の順に判定する。
+// Organizationで作成した組織ごとのカレンダーは、組織の指定がない日を会社のカレンダーで判定する
+type Calendar struct {
+	// Location 日付を判定するタイムゾーン。nilの場合はakashi.Location
+	Location *time.Location
+	// Weekends 週休日。nilの場合は土曜日と日曜日(組織のカレンダーは会社の設定)
+	Weekends []time.Weekday
+	// IgnoreNationalHolidays 国民の祝日を営業日として扱う
+	IgnoreNationalHolidays bool
+
+	parent   *Calendar
+	closures map[dateKey]string
+	workdays map[dateKey]string
+
+	mu   sync.Mutex
+	orgs map[int]*Calendar
+}
+
+// DefaultWeekends 標準の週休日
+var DefaultWeekends = []time.Weekday{time.Saturday, time.Sunday}
+
+// New 国民の祝日と土日を休日とするカレンダーを返す
+func New() *Calendar {
+	return &Calendar{}
+}
+
+// AddClosure tの日を休業日にする
+func (c *Calendar) AddClosure(t time.Time, name string) {
+	if c.closures == nil {
+		c.closures = map[dateKey]string{}
+	}
+	k := keyOf(t)
+	c.closures[k] = name
+	delete(c.workdays, k)
+}
+
+// AddWorkday tの日を週休日や祝日でも出勤日にする
+func (c *Calendar) AddWorkday(t time.Time, name string) {
+	if c.workdays == nil {
+		c.workdays = map[dateKey]string{}
+	}
+	k := keyOf(t)
+	c.workdays[k] = name
+	delete(c.closures, k)
+}
+
+// Organization 組織IDごとのカレンダーを返す。存在しない場合は作成する
+func (c *Calendar) Organization(orgID int) *Calendar {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.orgs == nil {
+		c.orgs = map[int]*Calendar{}
+	}
+	org, ok := c.orgs[orgID]
+	if !ok {
+		org = &Calendar{parent: c}
+		c.orgs[orgID] = org
+	}
+	return org
+}
+
+// ForStaff 従業員の所属組織(メイン)のカレンダーを返す
+// 組織の指定がない場合は会社のカレンダーを返す
+func (c *Calendar) ForStaff(s akashi.Staff) *Calendar {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if org, ok := c.orgs[s.Organization.ID]; ok {
+		return org
+	}
+	return c
+}
+
+// Holiday tの日が休日の場合はその名称を返す
+// 週休日の名称は空
+func (c *Calendar) Holiday(t time.Time) (string, bool) {
+	t = t.In(c.location())
+	k := keyOf(t)
+	for cal := c; cal != nil; cal = cal.parent {
+		if _, ok := cal.workdays[k]; ok {
+			return "", false
+		}
+		if name, ok := cal.closures[k]; ok {
+			return name, true
+		}
+	}
+	if !c.root().IgnoreNationalHolidays {
+		if name, ok := NationalHoliday(t); ok {
+			return name, true
+		}
+	}
+	for _, wd := range c.weekends() {
+		if t.Weekday() == wd {
+			return "", true
+		}
+	}
+	return "", false
+}
+
+// IsWorkday tの日が営業日か
+func (c *Calendar) IsWorkday(t time.Time) bool {
+	_, holiday := c.Holiday(t)
+	return !holiday
+}
+
+// NextWorkday tの翌日以降で最初の営業日(0時)を返す
+// 1年以内に営業日がない場合はゼロ値
+func (c *Calendar) NextWorkday(t time.Time) time.Time {
+	d := c.startOfDay(t)
+	for i := 0; i < 366; i++ {
+		d = d.AddDate(0, 0, 1)
+		if c.IsWorkday(d) {
+			return d
+		}
+	}
+	return time.Time{}
+}
+
+// WorkdaysInMonth year年month月の営業日(0時)を日付順に返す
+func (c *Calendar) WorkdaysInMonth(year int, month time.Month) []time.Time {
+	var days []time.Time
+	for d := time.Date(year, month, 1, 0, 0, 0, 0, c.location()); d.Month() == month; d = d.AddDate(0, 0, 1) {
+		if c.IsWorkday(d) {
+			days = append(days, d)
+		}
+	}
+	return days
+}
+
+func (c *Calendar) root() *Calendar {
+	for c.parent != nil {
+		c = c.parent
+	}
+	return c
+}
+
+func (c *Calendar) location() *time.Location {
+	if loc := c.root().Location; loc != nil {
+		return loc
+	}
+	return akashi.Location
+}
+
+func (c *Calendar) weekends() []time.Weekday {
+	for cal := c; cal != nil; cal = cal.parent {
+		if cal.Weekends != nil {
+			return cal.Weekends
+		}
+	}
+	return DefaultWeekends
+}
+
+func (c *Calendar) startOfDay(t time.Time) time.Time {
+	t = t.In(c.location())
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, c.location())
+}
+
+// holidayCache 年ごとの国民の祝日
+var holidayCache sync.Map
+
+// NationalHoliday tの日(tのタイムゾーンでの日付)が国民の祝日・振替休日・国民の休日の場合はその名称を返す
+func NationalHoliday(t time.Time) (string, bool) {
+	k := keyOf(t)
+	v, ok := holidayCache.Load(k.year)
+	if !ok {
+		m := map[dateKey]string{}
+		for _, h := range NationalHolidays(k.year) {
+			m[keyOf(h.Date)] = h.Name
+		}
+		v, _ = holidayCache.LoadOrStore(k.year, m)
+	}
+	name, ok := v.(map[dateKey]string)[k]
+	return name, ok
+}
@@ -0,0 +1,180 @@
+package calendar_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"hapoon/go-akashi/pkg/akashi"
+	"hapoon/go-akashi/pkg/akashi/calendar"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func day(year int, month time.Month, d int) time.Time {
+	return time.Date(year, month, d, 0, 0, 0, 0, akashi.Location)
+}
+
+func TestNationalHolidays(t *testing.T) {
+	var got []string
+	for _, h := range calendar.NationalHolidays(2026) {
+		got = append(got, h.Date.Format("01-02")+" "+h.Name)
+	}
+	assert.Equal(t, []string{
+		"01-01 元日",
+		"01-12 成人の日",
+		"02-11 建国記念の日",
+		"02-23 天皇誕生日",
+		"03-20 春分の日",
+		"04-29 昭和の日",
+		"05-03 憲法記念日",
+		"05-04 みどりの日",
+		"05-05 こどもの日",
+		"05-06 振替休日",
+		"07-20 海の日",
+		"08-11 山の日",
+		"09-21 敬老の日",
+		"09-22 国民の休日",
+		"09-23 秋分の日",
+		"10-12 スポーツの日",
+		"11-03 文化の日",
+		"11-23 勤労感謝の日",
+	}, got)
+}
+
+func TestNationalHoliday(t *testing.T) {
+	tests := []struct {
+		date time.Time
+		name string
+	}{
+		{day(2019, 4, 30), "国民の休日"},
+		{day(2019, 5, 1), "天皇の即位の日"},
+		{day(2019, 5, 6), "振替休日"},
+		{day(2019, 10, 22), "即位礼正殿の儀"},
+		{day(2020, 7, 24), "スポーツの日"},
+		{day(2021, 8, 9), "振替休日"},
+		{day(2009, 9, 22), "国民の休日"},
+		{day(1999, 1, 15), "成人の日"},
+		{day(2018, 12, 24), "振替休日"},
+		{day(2027, 3, 21), "春分の日"},
+		{day(2027, 3, 22), "振替休日"},
+	}
+	for _, tt := range tests {
+		name, ok := calendar.NationalHoliday(tt.date)
+		assert.True(t, ok, tt.date.Format("2006-01-02"))
+		assert.Equal(t, tt.name, name, tt.date.Format("2006-01-02"))
+	}
+	for _, d := range []time.Time{day(2020, 10, 12), day(2019, 12, 23), day(2026, 9, 24)} {
+		_, ok := calendar.NationalHoliday(d)
+		assert.False(t, ok, d.Format("2006-01-02"))
+	}
+}
+
+func TestCalendar(t *testing.T) {
+	c := calendar.New()
+	c.AddClosure(day(2026, 10, 19), "創立記念日")
+	c.AddWorkday(day(2026, 10, 24), "全社出勤日")
+
+	assert.True(t, c.IsWorkday(day(2026, 10, 16)))
+	assert.False(t, c.IsWorkday(day(2026, 10, 17)))
+	assert.False(t, c.IsWorkday(day(2026, 10, 12)))
+	assert.True(t, c.IsWorkday(day(2026, 10, 24)))
+	name, ok := c.Holiday(day(2026, 10, 19).Add(23 * time.Hour))
+	assert.True(t, ok)
+	assert.Equal(t, "創立記念日", name)
+
+	// 金曜日の夜の次は土日と休業日を飛ばして火曜日
+	next := c.NextWorkday(time.Date(2026, 10, 16, 22, 0, 0, 0, akashi.Location))
+	assert.True(t, day(2026, 10, 20).Equal(next), next)
+
+	// 2026年9月: 30日 - 土日8日 - 祝日3日(21〜23日)
+	assert.Len(t, c.WorkdaysInMonth(2026, time.September), 19)
+	// 2026年10月: 31日 - 土日9日 - 祝日1日 - 休業日1日 + 出勤日1日
+	assert.Len(t, c.WorkdaysInMonth(2026, time.October), 21)
+}
+
+func TestOrganization(t *testing.T) {
+	c := calendar.New()
+	c.AddClosure(day(2026, 12, 30), "年末休業")
+	org := c.Organization(10)
+	org.Weekends = []time.Weekday{time.Sunday}
+	org.AddWorkday(day(2026, 12, 30), "店舗営業")
+
+	assert.False(t, c.IsWorkday(day(2026, 12, 30)))
+	assert.True(t, org.IsWorkday(day(2026, 12, 30)))
+	assert.True(t, org.IsWorkday(day(2026, 12, 26))) // 土曜日
+	assert.False(t, org.IsWorkday(day(2026, 11, 3))) // 祝日は会社と同じ
+
+	assert.True(t, org == c.ForStaff(akashi.Staff{Organization: akashi.Organization{ID: 10}}))
+	assert.True(t, c == c.ForStaff(akashi.Staff{Organization: akashi.Organization{ID: 20}}))
+}
+
+func TestLoadYAML(t *testing.T) {
+	src := `
+weekends: [sun]
+closures:
+  - date: 2026-12-29
+    to: 2027-01-03
+    name: 年末年始休業
+workdays:
+  - date: 2026-11-03
+organizations:
+  10:
+    weekends: [saturday, sunday]
+    closures:
+      - date: 2026-08-14
+        name: 夏季休業
+`
+	c := calendar.New()
+	if !assert.NoError(t, c.LoadYAML(strings.NewReader(src))) {
+		return
+	}
+	assert.True(t, c.IsWorkday(day(2026, 10, 17)))  // 土曜日
+	assert.True(t, c.IsWorkday(day(2026, 11, 3)))   // 祝日の出勤日
+	assert.False(t, c.IsWorkday(day(2026, 12, 31))) // 年末年始
+	assert.False(t, c.IsWorkday(day(2027, 1, 3)))
+	assert.True(t, c.IsWorkday(day(2027, 1, 4)))
+
+	org := c.Organization(10)
+	assert.False(t, org.IsWorkday(day(2026, 10, 17)))
+	assert.False(t, org.IsWorkday(day(2026, 8, 14)))
+	assert.True(t, c.IsWorkday(day(2026, 8, 14)))
+
+	assert.Error(t, calendar.New().LoadYAML(strings.NewReader("weekends: [someday]")))
+	assert.Error(t, calendar.New().LoadYAML(strings.NewReader("closures:\n  - date: 2026/12/29")))
+}
+
+func TestLoadICS(t *testing.T) {
+	src := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"BEGIN:VEVENT",
+		"DTSTART;VALUE=DATE:20260813",
+		"DTEND;VALUE=DATE:20260817",
+		"SUMMARY:夏季休業\\, 全社",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"DTSTART:20261104T000000Z",
+		"DTEND:20261104T090000Z",
+		"SUMMARY:棚卸",
+		"  日",
+		"END:VEVENT",
+		"END:VCALENDAR",
+	}, "\r\n")
+	c := calendar.New()
+	if !assert.NoError(t, c.LoadICS(strings.NewReader(src))) {
+		return
+	}
+	name, ok := c.Holiday(day(2026, 8, 13))
+	assert.True(t, ok)
+	assert.Equal(t, "夏季休業, 全社", name)
+	assert.False(t, c.IsWorkday(day(2026, 8, 14)))
+	assert.True(t, c.IsWorkday(day(2026, 8, 17)))
+
+	name, ok = c.Holiday(day(2026, 11, 4))
+	assert.True(t, ok)
+	assert.Equal(t, "棚卸 日", name)
+	assert.True(t, c.IsWorkday(day(2026, 11, 5)))
+
+	assert.Error(t, calendar.New().LoadICS(strings.NewReader("BEGIN:VEVENT\nSUMMARY:x\nEND:VEVENT")))
+}
@@ -0,0 +1,212 @@
+package calendar
+
+import (
+	"sort"
+	"time"
+)
+
+// Holiday 祝日
+type Holiday struct {
+	Date time.Time // 日付(UTCの0時)
+	Name string    // 名称
+}
+
+// 祝日法の施行・改正日
+var (
+	// substituteSince 振替休日の施行日
+	substituteSince = date(1973, time.April, 12)
+	// substituteAnydaySince 振替休日が月曜日以外にもなる改正の施行日
+	substituteAnydaySince = date(2007, time.January, 1)
+	// citizensSince 国民の休日の施行日
+	citizensSince = date(1985, time.December, 27)
+)
+
+// specialHolidays 特別に定められた休日
+var specialHolidays = []Holiday{
+	{date(1959, time.April, 10), "皇太子明仁親王の結婚の儀"},
+	{date(1989, time.February, 24), "昭和天皇の大喪の礼"},
+	{date(1990, time.November, 12), "即位礼正殿の儀"},
+	{date(1993, time.June, 9), "皇太子徳仁親王の結婚の儀"},
+	{date(2019, time.May, 1), "天皇の即位の日"},
+	{date(2019, time.October, 22), "即位礼正殿の儀"},
+}
+
+// NationalHolidays year年の国民の祝日・振替休日・国民の休日を日付順に返す
+// 祝日法の施行(1948年)以前の年は空
+func NationalHolidays(year int) []Holiday {
+	if year < 1949 {
+		return nil
+	}
+	byDate := map[time.Time]string{}
+	for _, h := range statutoryHolidays(year) {
+		byDate[h.Date] = h.Name
+	}
+	for _, h := range specialHolidays {
+		if h.Date.Year() == year {
+			byDate[h.Date] = h.Name
+		}
+	}
+
+	// 振替休日: 日曜日の祝日の後の最初の祝日でない日(2006年までは翌月曜日のみ)
+	var substitutes []time.Time
+	for d := range byDate {
+		if d.Weekday() != time.Sunday || d.Before(substituteSince) {
+			continue
+		}
+		next := d.AddDate(0, 0, 1)
+		if !d.Before(substituteAnydaySince) {
+			for byDate[next] != "" {
+				next = next.AddDate(0, 0, 1)
+			}
+		} else if byDate[next] != "" {
+			continue
+		}
+		substitutes = append(substitutes, next)
+	}
+	for _, d := range substitutes {
+		if d.Year() == year {
+			byDate[d] = "振替休日"
+		}
+	}
+
+	// 国民の休日: 前日と翌日が祝日の平日
+	start := date(year, time.January, 1)
+	for d := start; d.Year() == year; d = d.AddDate(0, 0, 1) {
+		if d.Before(citizensSince) || byDate[d] != "" || d.Weekday() == time.Sunday {
+			continue
+		}
+		if isStatutory(byDate[d.AddDate(0, 0, -1)]) && isStatutory(byDate[d.AddDate(0, 0, 1)]) {
+			byDate[d] = "国民の休日"
+		}
+	}
+
+	holidays := make([]Holiday, 0, len(byDate))
+	for d, name := range byDate {
+		holidays = append(holidays, Holiday{Date: d, Name: name})
+	}
+	sort.Slice(holidays, func(i, j int) bool { return holidays[i].Date.Before(holidays[j].Date) })
+	return holidays
+}
+
+// isStatutory 国民の休日の判定に使う祝日か(振替休日・国民の休日は含まない)
+func isStatutory(name string) bool {
+	return name != "" && name != "振替休日" && name != "国民の休日"
+}
+
+// statutoryHolidays year年の国民の祝日(振替休日・国民の休日を除く)
+func statutoryHolidays(year int) []Holiday {
+	var hs []Holiday
+	add := func(m time.Month, d int, name string) {
+		hs = append(hs, Holiday{date(year, m, d), name})
+	}
+
+	add(time.January, 1, "元日")
+	if year < 2000 {
+		add(time.January, 15, "成人の日")
+	} else {
+		add(time.January, nthMonday(year, time.January, 2), "成人の日")
+	}
+	if year >= 1967 {
+		add(time.February, 11, "建国記念の日")
+	}
+	if year >= 2020 {
+		add(time.February, 23, "天皇誕生日")
+	}
+	add(time.March, vernalEquinox(year), "春分の日")
+	switch {
+	case year >= 2007:
+		add(time.April, 29, "昭和の日")
+	case year >= 1989:
+		add(time.April, 29, "みどりの日")
+	default:
+		add(time.April, 29, "天皇誕生日")
+	}
+	add(time.May, 3, "憲法記念日")
+	if year >= 2007 {
+		add(time.May, 4, "みどりの日")
+	}
+	add(time.May, 5, "こどもの日")
+	switch {
+	case year == 2020:
+		add(time.July, 23, "海の日")
+	case year == 2021:
+		add(time.July, 22, "海の日")
+	case year >= 2003:
+		add(time.July, nthMonday(year, time.July, 3), "海の日")
+	case year >= 1996:
+		add(time.July, 20, "海の日")
+	}
+	switch {
+	case year == 2020:
+		add(time.August, 10, "山の日")
+	case year == 2021:
+		add(time.August, 8, "山の日")
+	case year >= 2016:
+		add(time.August, 11, "山の日")
+	}
+	switch {
+	case year >= 2003:
+		add(time.September, nthMonday(year, time.September, 3), "敬老の日")
+	case year >= 1966:
+		add(time.September, 15, "敬老の日")
+	}
+	add(time.September, autumnalEquinox(year), "秋分の日")
+	switch {
+	case year == 2020:
+		add(time.July, 24, "スポーツの日")
+	case year == 2021:
+		add(time.July, 23, "スポーツの日")
+	case year >= 2022:
+		add(time.October, nthMonday(year, time.October, 2), "スポーツの日")
+	case year >= 2000:
+		add(time.October, nthMonday(year, time.October, 2), "体育の日")
+	case year >= 1966:
+		add(time.October, 10, "体育の日")
+	}
+	add(time.November, 3, "文化の日")
+	add(time.November, 23, "勤労感謝の日")
+	if year >= 1989 && year <= 2018 {
+		add(time.December, 23, "天皇誕生日")
+	}
+	return hs
+}
+
+// vernalEquinox 春分日(国立天文台の近似式)
+func vernalEquinox(year int) int {
+	switch {
+	case year < 1980:
+		return equinox(year, 20.8357, 1983)
+	case year < 2100:
+		return equinox(year, 20.8431, 1980)
+	default:
+		return equinox(year, 21.8510, 1980)
+	}
+}
+
+// autumnalEquinox 秋分日(国立天文台の近似式)
+func autumnalEquinox(year int) int {
+	switch {
+	case year < 1980:
+		return equinox(year, 23.2588, 1983)
+	case year < 2100:
+		return equinox(year, 23.2488, 1980)
+	default:
+		return equinox(year, 24.2488, 1980)
+	}
+}
+
+func equinox(year int, base float64, leapBase int) int {
+	return int(base + 0.242194*float64(year-1980) - float64((year-leapBase)/4))
+}
+
+// nthMonday year年month月の第n月曜日
+func nthMonday(year int, month time.Month, n int) int {
+	first := date(year, month, 1)
+	offset := (int(time.Monday) - int(first.Weekday()) + 7) % 7
+	return 1 + offset + 7*(n-1)
+}
+
+// date UTCの0時
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
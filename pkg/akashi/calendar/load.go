@@ -0,0 +1,266 @@
+package calendar
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// File YAMLのカレンダーファイルの形式
+//
+//	weekends: [saturday, sunday]
+//	national_holidays: true
+//	closures:
+//	  - date: 2026-12-29
+//	    to: 2027-01-03
+//	    name: 年末年始休業
+//	workdays:
+//	  - date: 2026-11-03
+//	    name: 全社出勤日
+//	organizations:
+//	  10:
+//	    weekends: [sunday]
+//	    closures:
+//	      - date: 2026-08-13
+//	        to: 2026-08-16
+//	        name: 夏季休業
+type File struct {
+	Weekends         []string        `yaml:"weekends"`          // 週休日
+	NationalHolidays *bool           `yaml:"national_holidays"` // 国民の祝日を休日にするか(省略時はtrue)
+	Closures         []Entry         `yaml:"closures"`          // 休業日
+	Workdays         []Entry         `yaml:"workdays"`          // 出勤日
+	Organizations    map[int]OrgFile `yaml:"organizations"`     // 組織IDごとの指定
+}
+
+// OrgFile 組織ごとの指定
+type OrgFile struct {
+	Weekends []string `yaml:"weekends"` // 週休日(省略時は会社の設定)
+	Closures []Entry  `yaml:"closures"` // 休業日
+	Workdays []Entry  `yaml:"workdays"` // 出勤日
+}
+
+// Entry 日付または期間の指定
+type Entry struct {
+	Date string `yaml:"date"` // 日付(YYYY-MM-DD)
+	To   string `yaml:"to"`   // 期間の最終日(YYYY-MM-DD)。省略時はDateの1日のみ
+	Name string `yaml:"name"` // 名称
+}
+
+// LoadFile YAML(.yaml, .yml)またはiCalendar(.ics)のファイルを読み込む
+func (c *Calendar) LoadFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = c.LoadYAML(f)
+	case ".ics":
+		err = c.LoadICS(f)
+	default:
+		return fmt.Errorf("calendar: unsupported file type %q", path)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// LoadYAML YAMLのカレンダーを読み込む
+func (c *Calendar) LoadYAML(r io.Reader) error {
+	var f File
+	if err := yaml.NewDecoder(r).Decode(&f); err != nil && err != io.EOF {
+		return fmt.Errorf("calendar: %w", err)
+	}
+	if f.NationalHolidays != nil {
+		c.IgnoreNationalHolidays = !*f.NationalHolidays
+	}
+	if err := c.apply(f.Weekends, f.Closures, f.Workdays); err != nil {
+		return err
+	}
+	for id, org := range f.Organizations {
+		if err := c.Organization(id).apply(org.Weekends, org.Closures, org.Workdays); err != nil {
+			return fmt.Errorf("organizations.%d: %w", id, err)
+		}
+	}
+	return nil
+}
+
+func (c *Calendar) apply(weekends []string, closures, workdays []Entry) error {
+	if weekends != nil {
+		wds := make([]time.Weekday, 0, len(weekends))
+		for _, s := range weekends {
+			wd, err := ParseWeekday(s)
+			if err != nil {
+				return err
+			}
+			wds = append(wds, wd)
+		}
+		c.Weekends = wds
+	}
+	for _, e := range closures {
+		if err := c.each(e, c.AddClosure); err != nil {
+			return err
+		}
+	}
+	for _, e := range workdays {
+		if err := c.each(e, c.AddWorkday); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// each 指定の期間の各日にfnを適用する
+func (c *Calendar) each(e Entry, fn func(time.Time, string)) error {
+	from, err := time.ParseInLocation("2006-01-02", e.Date, c.location())
+	if err != nil {
+		return fmt.Errorf("calendar: date: %w", err)
+	}
+	to := from
+	if e.To != "" {
+		if to, err = time.ParseInLocation("2006-01-02", e.To, c.location()); err != nil {
+			return fmt.Errorf("calendar: to: %w", err)
+		}
+		if to.Before(from) {
+			return fmt.Errorf("calendar: %s is before %s", e.To, e.Date)
+		}
+	}
+	for d := from; !d.After(to); d = d.AddDate(0, 0, 1) {
+		fn(d, e.Name)
+	}
+	return nil
+}
+
+// LoadICS iCalendarの予定を休業日として読み込む
+// DTSTARTからDTEND(含まない)までの各日を休業日とし、SUMMARYを名称とする。RRULEには対応しない
+func (c *Calendar) LoadICS(r io.Reader) error {
+	lines, err := unfold(r)
+	if err != nil {
+		return fmt.Errorf("calendar: %w", err)
+	}
+	var (
+		inEvent    bool
+		start, end time.Time
+		allDay     bool
+		summary    string
+	)
+	for n, line := range lines {
+		name, params, value := contentLine(line)
+		switch {
+		case name == "BEGIN" && value == "VEVENT":
+			inEvent = true
+			start, end, allDay, summary = time.Time{}, time.Time{}, false, ""
+		case name == "END" && value == "VEVENT":
+			inEvent = false
+			if start.IsZero() {
+				return fmt.Errorf("calendar: line %d: VEVENT without DTSTART", n+1)
+			}
+			last := start
+			if !end.IsZero() {
+				// DTENDは含まない
+				if allDay {
+					last = end.AddDate(0, 0, -1)
+				} else {
+					last = end.Add(-time.Nanosecond)
+				}
+			}
+			first := c.startOfDay(start)
+			for d := first; !d.After(last); d = d.AddDate(0, 0, 1) {
+				c.AddClosure(d, summary)
+			}
+		case !inEvent:
+		case name == "DTSTART" || name == "DTEND":
+			t, date, err := c.parseICSTime(params, value)
+			if err != nil {
+				return fmt.Errorf("calendar: line %d: %s: %w", n+1, name, err)
+			}
+			if name == "DTSTART" {
+				start, allDay = t, date
+			} else {
+				end = t
+			}
+		case name == "SUMMARY":
+			summary = unescapeText(value)
+		}
+	}
+	if inEvent {
+		return fmt.Errorf("calendar: unterminated VEVENT")
+	}
+	return nil
+}
+
+// unfold 折り返された行を連結する
+func unfold(r io.Reader) ([]string, error) {
+	var lines []string
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		line := strings.TrimRight(s.Text(), "\r")
+		if len(lines) > 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines, s.Err()
+}
+
+// contentLine NAME;PARAM=VALUE:VALUEの形式の行を分解する
+func contentLine(line string) (string, map[string]string, string) {
+	i := strings.Index(line, ":")
+	if i < 0 {
+		return "", nil, ""
+	}
+	parts := strings.Split(line[:i], ";")
+	params := map[string]string{}
+	for _, p := range parts[1:] {
+		if kv := strings.SplitN(p, "=", 2); len(kv) == 2 {
+			params[strings.ToUpper(kv[0])] = strings.Trim(kv[1], `"`)
+		}
+	}
+	return strings.ToUpper(parts[0]), params, line[i+1:]
+}
+
+// parseICSTime DATEまたはDATE-TIMEの値を解釈する。日付のみの場合はdateがtrue
+func (c *Calendar) parseICSTime(params map[string]string, value string) (t time.Time, date bool, err error) {
+	if params["VALUE"] == "DATE" || len(value) == len("20060102") {
+		t, err = time.ParseInLocation("20060102", value, c.location())
+		return t, true, err
+	}
+	if strings.HasSuffix(value, "Z") {
+		t, err = time.Parse("20060102T150405Z", value)
+		return t, false, err
+	}
+	loc := c.location()
+	if tzid := params["TZID"]; tzid != "" {
+		if l, err := time.LoadLocation(tzid); err == nil {
+			loc = l
+		}
+	}
+	t, err = time.ParseInLocation("20060102T150405", value, loc)
+	return t, false, err
+}
+
+var textEscapes = strings.NewReplacer(`\\`, `\`, `\,`, ",", `\;`, ";", `\n`, "\n", `\N`, "\n")
+
+func unescapeText(s string) string {
+	return textEscapes.Replace(s)
+}
+
+// ParseWeekday 曜日の名前(Sunday, sunなど。大文字小文字は区別しない)を解釈する
+func ParseWeekday(s string) (time.Weekday, error) {
+	for wd := time.Sunday; wd <= time.Saturday; wd++ {
+		name := wd.String()
+		if strings.EqualFold(s, name) || strings.EqualFold(s, name[:3]) {
+			return wd, nil
+		}
+	}
+	return 0, fmt.Errorf("calendar: unknown weekday %q", s)
+}
@@ -4,64 +4,266 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
-	"log"
+	"io"
 	"net/http"
+	neturl "net/url"
+	"strings"
+	"time"
 )
 
-var (
-	endpointURL = "https://atnd.ak4.jp/api/cooperation"
+const (
+	// DefaultBaseURL AKASHI公開APIのベースURL
+	DefaultBaseURL = "https://atnd.ak4.jp/api/cooperation"
+	// DefaultUserAgent デフォルトのUser-Agent
+	DefaultUserAgent = "go-akashi"
 )
 
-// Client is client interface
-type Client interface {
-	Get(ctx context.Context, url string) (*http.Response, error)
-	Post(ctx context.Context, url string, body interface{}) (*http.Response, error)
+// DefaultClient パッケージレベルの関数が利用するクライアント
+var DefaultClient = NewClient()
+
+// Client AKASHI APIクライアント
+type Client struct {
+	hc        *http.Client
+	baseURL   string
+	userAgent string
+	timeout   time.Duration
+	logger    Logger
+	retry     RetryPolicy
+	limiter   *RateLimiter
+	tokens    TokenSource
+	now       func() time.Time
 }
 
-type client struct {
-	hc *http.Client
-	cc string // company_code
-	t  string // access_token
+// Option クライアントの設定
+type Option func(*Client)
+
+// WithBaseURL APIのベースURLを指定する
+func WithBaseURL(u string) Option {
+	return func(c *Client) {
+		c.baseURL = strings.TrimSuffix(u, "/")
+	}
 }
 
-func (c client) Get(ctx context.Context, url string) (*http.Response, error) {
-	endpoint := endpointURL + url
-	log.Println("GET", endpoint)
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
-	if err != nil {
-		return nil, err
+// WithHTTPClient リクエストに利用するhttp.Clientを指定する
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) {
+		if hc != nil {
+			c.hc = hc
+		}
 	}
-	res, err := c.hc.Do(req)
-	if err != nil {
-		return nil, err
+}
+
+// WithUserAgent User-Agentヘッダを指定する
+func WithUserAgent(ua string) Option {
+	return func(c *Client) {
+		c.userAgent = ua
+	}
+}
+
+// WithTimeout 1リクエストあたりのタイムアウトを指定する
+func WithTimeout(d time.Duration) Option {
+	return func(c *Client) {
+		c.timeout = d
+	}
+}
+
+// WithLogger リクエストログの出力先を指定する
+// 指定しない場合は何も出力しない
+func WithLogger(l Logger) Option {
+	return func(c *Client) {
+		if l == nil {
+			l = nopLogger{}
+		}
+		c.logger = l
+	}
+}
+
+// WithRetry リトライポリシーを指定する
+// GETと再送可能と明示されたPOSTのみがリトライの対象となる
+func WithRetry(p RetryPolicy) Option {
+	return func(c *Client) {
+		c.retry = p
+	}
+}
+
+// WithRateLimit 1秒あたりrps回、最大burst回まで連続でリクエストするように制限する
+func WithRateLimit(rps float64, burst int) Option {
+	return func(c *Client) {
+		c.limiter = NewRateLimiter(rps, burst)
+	}
+}
+
+// WithRateLimiter 他のクライアントと共有するRateLimiterを指定する
+// 同じ企業IDを利用するクライアント間ではSharedRateLimiterで取得したものを指定する
+func WithRateLimiter(l *RateLimiter) Option {
+	return func(c *Client) {
+		c.limiter = l
+	}
+}
+
+// WithTokenSource リクエストに利用するアクセストークンの提供元を指定する
+// 指定した場合は各パラメータのTokenより優先され、トークンの期限切れで
+// 失敗したリクエストはトークンを再取得して1回だけ再送する
+func WithTokenSource(ts TokenSource) Option {
+	return func(c *Client) {
+		c.tokens = ts
+	}
+}
+
+// WithClock 現在時刻の取得方法を指定する
+// 打刻の重複判定で打刻日時を指定しない場合の基準に利用する。テストで時刻を固定する場合に指定する
+func WithClock(now func() time.Time) Option {
+	return func(c *Client) {
+		if now != nil {
+			c.now = now
+		}
 	}
-	return res, nil
 }
 
-func (c client) Post(ctx context.Context, url string, body interface{}) (*http.Response, error) {
-	endpoint := endpointURL + url
-	log.Println("POST", endpoint)
+// NewClient is constructor
+func NewClient(opts ...Option) *Client {
+	c := &Client{
+		hc:        &http.Client{},
+		baseURL:   DefaultBaseURL,
+		userAgent: DefaultUserAgent,
+		logger:    nopLogger{},
+		now:       time.Now,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// BaseURL APIのベースURLを返す
+func (c *Client) BaseURL() string {
+	return c.baseURL
+}
+
+// Get GETリクエストを送信する
+// レスポンスボディは呼び出し側で閉じる必要がある
+func (c *Client) Get(ctx context.Context, url string) (*http.Response, error) {
+	return c.send(ctx, http.MethodGet, url, nil, true)
+}
+
+// Post bodyをJSONにしてPOSTリクエストを送信する
+// POSTはリトライしない
+// レスポンスボディは呼び出し側で閉じる必要がある
+func (c *Client) Post(ctx context.Context, url string, body interface{}) (*http.Response, error) {
+	return c.post(ctx, url, body, false)
+}
+
+// post idempotentがtrueの場合はリトライポリシーに従って再送する
+func (c *Client) post(ctx context.Context, url string, body interface{}, idempotent bool) (*http.Response, error) {
 	b, err := json.Marshal(body)
 	if err != nil {
 		return nil, err
 	}
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewBuffer(b))
-	req.Header.Set("Content-Type", "application/json")
-	if err != nil {
-		return nil, err
+	return c.send(ctx, http.MethodPost, url, b, idempotent)
+}
+
+// send リクエストを送信する
+// retryableがtrueの場合は失敗時にリトライポリシーに従って再送する
+func (c *Client) send(ctx context.Context, method, url string, body []byte, retryable bool) (*http.Response, error) {
+	endpoint := c.baseURL + url
+	maxAttempts := 1
+	if retryable && c.retry.MaxAttempts > 1 {
+		maxAttempts = c.retry.MaxAttempts
+	}
+	for attempt := 1; ; attempt++ {
+		if err := c.limiter.Wait(ctx); err != nil {
+			return nil, err
+		}
+		c.logRequest(method, endpoint, body, attempt)
+		start := time.Now()
+		res, err := c.attempt(ctx, method, endpoint, body)
+		c.logResponse(method, endpoint, res, err, time.Since(start))
+		if attempt >= maxAttempts || !shouldRetry(ctx, res, err) {
+			return res, err
+		}
+		wait, ok := c.retry.delay(attempt, res)
+		if !ok {
+			return res, err
+		}
+		if res != nil {
+			closeBody(res.Body)
+		}
+		c.logger.Log(LevelWarn, "retrying request", "method", method, "url", RedactURL(endpoint), "attempt", attempt, "wait", wait)
+		if err := sleep(ctx, wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// attempt 1回分のリクエストを送信する
+func (c *Client) attempt(ctx context.Context, method, endpoint string, body []byte) (*http.Response, error) {
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		res, err := c.do(ctx, method, endpoint, body)
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		res.Body = &cancelBody{ReadCloser: res.Body, cancel: cancel}
+		return res, nil
+	}
+	return c.do(ctx, method, endpoint, body)
+}
+
+func (c *Client) do(ctx context.Context, method, endpoint string, body []byte) (*http.Response, error) {
+	var req *http.Request
+	var err error
+	if body != nil {
+		req, err = http.NewRequestWithContext(ctx, method, endpoint, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+	} else {
+		req, err = http.NewRequestWithContext(ctx, method, endpoint, nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if c.userAgent != "" {
+		req.Header.Set("User-Agent", c.userAgent)
 	}
 	res, err := c.hc.Do(req)
 	if err != nil {
+		// エラーメッセージにアクセストークンが含まれないようにする
+		if ue, ok := err.(*neturl.Error); ok {
+			ue.URL = RedactURL(ue.URL)
+		}
 		return nil, err
 	}
 	return res, nil
 }
 
-// NewClient is constructor
-func NewClient(companyCode, token string) Client {
-	return &client{
-		hc: &http.Client{},
-		cc: companyCode,
-		t:  token,
+func (c *Client) logRequest(method, endpoint string, body []byte, attempt int) {
+	kv := []interface{}{"method", method, "url", RedactURL(endpoint), "attempt", attempt}
+	if body != nil {
+		kv = append(kv, "body", RedactBody(body))
 	}
+	c.logger.Log(LevelDebug, "sending request", kv...)
+}
+
+func (c *Client) logResponse(method, endpoint string, res *http.Response, err error, elapsed time.Duration) {
+	if err != nil {
+		c.logger.Log(LevelWarn, "request failed", "method", method, "url", RedactURL(endpoint), "error", err, "elapsed", elapsed)
+		return
+	}
+	c.logger.Log(LevelDebug, "received response", "method", method, "url", RedactURL(endpoint), "status", res.StatusCode, "header", RedactHeader(res.Header), "elapsed", elapsed)
+}
+
+// cancelBody ボディを閉じた時にタイムアウト用のcontextを解放する
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
 }
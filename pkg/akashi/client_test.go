@@ -1,114 +1,439 @@
-package akashi
+package akashi_test
 
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
-	"net/http/httputil"
 	"testing"
+	"time"
+
+	"hapoon/go-akashi/pkg/akashi"
+	"hapoon/go-akashi/pkg/akashi/akashitest"
 
 	"github.com/stretchr/testify/assert"
 )
 
-type testClient struct {
-	ID   int    `json:"id"`
-	Name string `json:"name"`
+const (
+	testCompanyCode = "abc"
+	testToken       = "test-token"
+	testStaffID     = 1
+)
+
+// newTestServer トークンと従業員を登録したテスト用サーバを起動する
+func newTestServer() *akashitest.Server {
+	srv := akashitest.NewServer()
+	srv.AddToken(testCompanyCode, testToken, testStaffID, time.Time{})
+	srv.AddStaff(testCompanyCode, akashi.Staff{ID: testStaffID, LastName: "山田", FirstName: "太郎"})
+	return srv
 }
 
 func TestClientGet(t *testing.T) {
-	cli := NewClient("", "")
+	srv := newTestServer()
+	defer srv.Close()
+	cli := srv.Client()
 	testCase := map[string]struct {
-		endpointURL string
-		statusCode  int
-		err         bool
-		foo1        string
-		foo2        string
-		timeout     bool
+		url        string
+		statusCode int
+		err        bool
+		timeout    bool
 	}{
-		"ok(args not exist)": {
-			endpointURL: "https://postman-echo.com/get",
-			statusCode:  http.StatusOK,
-			err:         false,
-		},
-		"ok(args exist)": {
-			endpointURL: "https://postman-echo.com/get?foo1=abc&foo2=1",
-			statusCode:  http.StatusOK,
-			err:         false,
-			foo1:        "abc",
-			foo2:        "1",
+		"ok": {
+			url:        "/abc/alerts?token=" + testToken,
+			statusCode: http.StatusOK,
+		},
+		"ok(unauthorized)": {
+			url:        "/abc/alerts?token=invalid",
+			statusCode: http.StatusUnauthorized,
 		},
 		"ok(not found)": {
-			endpointURL: "https://postman-echo.com/gett",
-			statusCode:  http.StatusNotFound,
-			err:         false,
+			url:        "/abc/alertss",
+			statusCode: http.StatusNotFound,
 		},
 		"ng": {
-			endpointURL: "https://postman-echo.com/get",
-			statusCode:  http.StatusNotFound,
-			err:         true,
-			timeout:     true,
+			url:     "/abc/alerts",
+			err:     true,
+			timeout: true,
 		},
 	}
 
 	for scenario, test := range testCase {
-		endpointURL = test.endpointURL
-		ctx := context.Background()
+		ctx, cancel := context.WithCancel(context.Background())
 		if test.timeout {
-			ctx, _ = context.WithTimeout(ctx, 0)
-		}
-		res, err := cli.Get(ctx, "")
-		var resBody struct {
-			Args struct {
-				Foo1 string `json:"foo1"`
-				Foo2 string `json:"foo2"`
-			} `json:"args"`
+			cancel()
 		}
+		res, err := cli.Get(ctx, test.url)
 		if test.err {
 			assert.Error(t, err, scenario)
 		} else {
 			assert.NoError(t, err, scenario)
 			assert.Equal(t, test.statusCode, res.StatusCode, scenario)
-			b, _ := httputil.DumpResponse(res, true)
-			t.Log(string(b))
-			if res.StatusCode == http.StatusOK {
-				json.NewDecoder(res.Body).Decode(&resBody)
-				assert.Equal(t, test.foo1, resBody.Args.Foo1, scenario)
-				assert.Equal(t, test.foo2, resBody.Args.Foo2, scenario)
-			}
+			res.Body.Close()
 		}
+		cancel()
 	}
 }
 
 func TestClientPost(t *testing.T) {
-	cli := NewClient("", "")
+	srv := newTestServer()
+	defer srv.Close()
+	cli := srv.Client()
 	ctx := context.Background()
+
+	body := map[string]interface{}{"token": testToken, "type": 11}
+	res, err := cli.Post(ctx, "/abc/stamps", body)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	res.Body.Close()
+
+	reqs := srv.Requests()
+	if assert.Len(t, reqs, 1) {
+		assert.Equal(t, http.MethodPost, reqs[0].Method)
+		assert.Equal(t, "application/json", reqs[0].Header.Get("Content-Type"))
+		var got map[string]interface{}
+		assert.NoError(t, json.Unmarshal(reqs[0].Body, &got))
+		assert.Equal(t, testToken, got["token"])
+	}
+}
+
+func TestClientOptions(t *testing.T) {
+	srv := newTestServer()
+	defer srv.Close()
+
+	cli := akashi.NewClient(akashi.WithBaseURL(srv.URL+"/"), akashi.WithUserAgent("test-agent"))
+	assert.Equal(t, srv.URL, cli.BaseURL())
+	_, err := cli.GetAlerts(context.Background(), akashi.GetAlertParam{LoginCompanyCode: testCompanyCode, Token: testToken})
+	assert.NoError(t, err)
+	assert.Equal(t, "test-agent", srv.Requests()[0].Header.Get("User-Agent"))
+
+	slow := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		<-req.Context().Done()
+		return nil, req.Context().Err()
+	})}
+	cli = srv.Client(akashi.WithHTTPClient(slow), akashi.WithTimeout(10*time.Millisecond))
+	_, err = cli.GetAlerts(context.Background(), akashi.GetAlertParam{LoginCompanyCode: testCompanyCode, Token: testToken})
+	assert.Error(t, err)
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestClientGetStaff(t *testing.T) {
+	srv := newTestServer()
+	defer srv.Close()
+	srv.AddStaff(testCompanyCode, akashi.Staff{ID: 2, LastName: "佐藤"})
+	cli := srv.Client()
+	ctx := context.Background()
+
 	tests := map[string]struct {
-		endpointURL string
-		statusCode  int
-		body        testClient
+		param akashi.GetStaffParam
+		ids   []int
+		total int
+		err   error
 	}{
-		"ok": {
-			endpointURL: "https://postman-echo.com/post",
-			statusCode:  http.StatusOK,
-			body: testClient{
-				ID:   1,
-				Name: "Bob",
-			},
+		"self": {
+			param: akashi.GetStaffParam{LoginCompanyCode: testCompanyCode, Token: testToken},
+			ids:   []int{1},
+			total: 2,
+		},
+		"staff id": {
+			param: akashi.GetStaffParam{LoginCompanyCode: testCompanyCode, Token: testToken, StaffID: 2},
+			ids:   []int{2},
+			total: 2,
+		},
+		"page": {
+			param: akashi.GetStaffParam{LoginCompanyCode: testCompanyCode, Token: testToken, Page: 1},
+			ids:   []int{1, 2},
+			total: 2,
+		},
+		"invalid token": {
+			param: akashi.GetStaffParam{LoginCompanyCode: testCompanyCode, Token: "invalid"},
+			err:   akashi.ErrInvalidToken,
+		},
+		"other company": {
+			param: akashi.GetStaffParam{LoginCompanyCode: "xyz", Token: testToken},
+			err:   akashi.ErrPermissionDenied,
 		},
 	}
-
 	for scenario, test := range tests {
-		endpointURL = test.endpointURL
-		res, err := cli.Post(ctx, "", test.body)
-		var resBody struct {
-			Args struct{}   `json:"args"`
-			Data testClient `json:"data"`
+		res, err := cli.GetStaff(ctx, test.param)
+		if test.err != nil {
+			assert.True(t, errors.Is(err, test.err), "%s: %v", scenario, err)
+			continue
 		}
 		assert.NoError(t, err, scenario)
-		assert.Equal(t, test.statusCode, res.StatusCode, scenario)
-		b, _ := httputil.DumpResponse(res, true)
-		t.Log(string(b))
-		json.NewDecoder(res.Body).Decode(&resBody)
-		assert.Equal(t, test.body, resBody.Data, scenario)
+		var ids []int
+		for _, v := range res.Staffs {
+			ids = append(ids, v.ID)
+		}
+		assert.Equal(t, test.ids, ids, scenario)
+		assert.Equal(t, len(test.ids), res.Count, scenario)
+		assert.Equal(t, test.total, res.TotalCount, scenario)
+	}
+}
+
+func TestClientStamps(t *testing.T) {
+	srv := newTestServer()
+	defer srv.Close()
+	now := time.Date(2026, 9, 1, 9, 0, 0, 0, akashi.Location)
+	srv.Now = func() time.Time { return now }
+	srv.AddStamps(testCompanyCode, testStaffID, akashi.Stamp{
+		StampedAt: akashi.NewAkTime(now.AddDate(0, 0, -1)),
+		Type:      akashi.StampTypeGoToWork,
+	})
+	cli := srv.Client()
+	ctx := context.Background()
+
+	res, err := cli.PostStamp(ctx, akashi.PostStampParam{LoginCompanyCode: testCompanyCode, Token: testToken, Type: akashi.StampTypeGoToWork})
+	assert.NoError(t, err)
+	assert.Equal(t, testStaffID, res.StaffID)
+	assert.Equal(t, akashi.StampTypeGoToWork, res.Type)
+	assert.True(t, now.Equal(res.StampedAt.Time))
+
+	res, err = cli.PostStamp(ctx, akashi.PostStampParam{LoginCompanyCode: testCompanyCode, Token: testToken})
+	assert.NoError(t, err)
+	assert.Equal(t, akashi.StampTypeLeaveWork, res.Type)
+
+	stamps, err := cli.GetStamps(ctx, akashi.GetStampParam{
+		LoginCompanyCode: testCompanyCode,
+		Token:            testToken,
+		StartDate:        now.Add(-time.Hour),
+		EndDate:          now.Add(time.Hour),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, testStaffID, stamps.StaffID)
+	assert.Equal(t, 2, stamps.Count)
+	assert.Len(t, srv.Stamps(testCompanyCode, testStaffID), 3)
+}
+
+// TestClientPostStampTimezone クライアントのタイムゾーンでの打刻日時
+func TestClientPostStampTimezone(t *testing.T) {
+	srv := newTestServer()
+	defer srv.Close()
+	cli := srv.Client()
+	ctx := context.Background()
+
+	ny := time.FixedZone("EDT", -4*60*60)
+	backdated := time.Date(2026, 8, 31, 19, 30, 0, 0, ny)
+	res, err := cli.PostStamp(ctx, akashi.PostStampParam{
+		LoginCompanyCode: testCompanyCode,
+		Token:            testToken,
+		Type:             akashi.StampTypeBreak,
+		StampedAt:        akashi.NewAkTime(backdated),
+		Timezone:         akashi.FormatTimezone(backdated),
+	})
+	assert.NoError(t, err)
+	// 打刻日時はサーバの時刻で、指定した日時はローカル打刻時刻として記録される
+	assert.False(t, backdated.Equal(res.StampedAt.Time), res.StampedAt)
+	stamps := srv.Stamps(testCompanyCode, testStaffID)
+	if assert.Len(t, stamps, 1) {
+		assert.True(t, backdated.Equal(stamps[0].LocalTime.Time), stamps[0].LocalTime)
+		assert.True(t, backdated.Equal(stamps[0].ClientTime()))
+		assert.Equal(t, "-04:00", stamps[0].Timezone)
 	}
+
+	_, err = cli.PostStamp(ctx, akashi.PostStampParam{LoginCompanyCode: testCompanyCode, Token: testToken, Timezone: "EDT"})
+	assert.Error(t, err)
+}
+
+func TestClientGetAlerts(t *testing.T) {
+	srv := newTestServer()
+	defer srv.Close()
+	srv.AddAlerts(testCompanyCode, testStaffID, akashi.Alert{Month: "2026/09", Date: "1", AlertType: akashi.AlertTypeLateness})
+	cli := srv.Client()
+
+	res, err := cli.GetAlerts(context.Background(), akashi.GetAlertParam{LoginCompanyCode: testCompanyCode, Token: testToken})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, res.Count)
+	assert.Equal(t, akashi.AlertTypeLateness, res.Alerts[0].AlertType)
+}
+
+func TestClientPostTokenReissue(t *testing.T) {
+	srv := newTestServer()
+	defer srv.Close()
+	cli := srv.Client()
+	ctx := context.Background()
+
+	res, err := cli.PostTokenReissue(ctx, akashi.PostTokenReissueParam{LoginCompanyCode: testCompanyCode, Token: testToken})
+	assert.NoError(t, err)
+	assert.NotEqual(t, testToken, res.Token)
+	assert.True(t, res.ExpiredAt.After(time.Now()))
+
+	// 古いトークンは無効になる
+	_, err = cli.GetAlerts(ctx, akashi.GetAlertParam{LoginCompanyCode: testCompanyCode, Token: testToken})
+	assert.True(t, errors.Is(err, akashi.ErrInvalidToken))
+	_, err = cli.GetAlerts(ctx, akashi.GetAlertParam{LoginCompanyCode: testCompanyCode, Token: res.Token})
+	assert.NoError(t, err)
+}
+
+func TestClientExpiredToken(t *testing.T) {
+	srv := newTestServer()
+	defer srv.Close()
+	srv.AddToken(testCompanyCode, "expired", testStaffID, time.Now().Add(-time.Minute))
+
+	_, err := srv.Client().GetAlerts(context.Background(), akashi.GetAlertParam{LoginCompanyCode: testCompanyCode, Token: "expired"})
+	assert.True(t, errors.Is(err, akashi.ErrTokenExpired), err)
+}
+
+func TestClientFaults(t *testing.T) {
+	tests := map[string]struct {
+		fault  akashitest.Fault
+		status int
+		target error
+	}{
+		"server error": {
+			fault:  akashitest.Fault{Kind: akashitest.FaultServerError},
+			status: http.StatusInternalServerError,
+		},
+		"rate limited": {
+			fault:  akashitest.Fault{Kind: akashitest.FaultRateLimited},
+			status: http.StatusTooManyRequests,
+			target: akashi.ErrRateLimited,
+		},
+		"failure": {
+			fault:  akashitest.Fault{Kind: akashitest.FaultFailure},
+			status: http.StatusOK,
+			target: akashi.ErrValidation,
+		},
+		"malformed json": {
+			fault: akashitest.Fault{Kind: akashitest.FaultMalformedJSON},
+		},
+	}
+	for scenario, test := range tests {
+		srv := newTestServer()
+		srv.InjectFault(test.fault)
+		_, err := srv.Client().GetAlerts(context.Background(), akashi.GetAlertParam{LoginCompanyCode: testCompanyCode, Token: testToken})
+		assert.Error(t, err, scenario)
+		var apiErr *akashi.APIError
+		assert.Equal(t, test.status != 0, errors.As(err, &apiErr), scenario)
+		if test.status != 0 {
+			assert.Equal(t, test.status, apiErr.StatusCode, scenario)
+			assert.Equal(t, "/abc/alerts", apiErr.Endpoint, scenario)
+		}
+		if test.target != nil {
+			assert.True(t, errors.Is(err, test.target), scenario)
+		}
+		srv.Close()
+	}
+}
+
+func TestClientRetry(t *testing.T) {
+	tests := map[string]struct {
+		method    string
+		retryable bool
+		fault     akashitest.Fault
+		attempts  int
+		err       bool
+	}{
+		"get recovers from 503": {
+			method:   http.MethodGet,
+			fault:    akashitest.Fault{Kind: akashitest.FaultUnavailable, Times: 1},
+			attempts: 2,
+		},
+		"get recovers from 429": {
+			method:   http.MethodGet,
+			fault:    akashitest.Fault{Kind: akashitest.FaultRateLimited, Times: 1, RetryAfter: 1},
+			attempts: 2,
+		},
+		"get gives up": {
+			method:   http.MethodGet,
+			fault:    akashitest.Fault{Kind: akashitest.FaultServerError},
+			attempts: 3,
+			err:      true,
+		},
+		"get does not retry failure": {
+			method:   http.MethodGet,
+			fault:    akashitest.Fault{Kind: akashitest.FaultFailure, Times: 1},
+			attempts: 1,
+			err:      true,
+		},
+		"post is not retried": {
+			method:   http.MethodPost,
+			fault:    akashitest.Fault{Kind: akashitest.FaultUnavailable, Times: 1},
+			attempts: 1,
+			err:      true,
+		},
+		"retryable post is retried": {
+			method:    http.MethodPost,
+			retryable: true,
+			fault:     akashitest.Fault{Kind: akashitest.FaultUnavailable, Times: 1},
+			attempts:  2,
+		},
+	}
+
+	for scenario, test := range tests {
+		srv := newTestServer()
+		srv.InjectFault(test.fault)
+		cli := srv.Client(akashi.WithRetry(akashi.RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 2 * time.Second}))
+		ctx := context.Background()
+		var err error
+		if test.method == http.MethodGet {
+			_, err = cli.GetAlerts(ctx, akashi.GetAlertParam{LoginCompanyCode: testCompanyCode, Token: testToken})
+		} else {
+			_, err = cli.PostStamp(ctx, akashi.PostStampParam{LoginCompanyCode: testCompanyCode, Token: testToken, Retryable: test.retryable})
+		}
+		assert.Equal(t, test.err, err != nil, scenario)
+		assert.Len(t, srv.Requests(), test.attempts, scenario)
+		srv.Close()
+	}
+}
+
+func TestClientPostStampDedupe(t *testing.T) {
+	srv := newTestServer()
+	defer srv.Close()
+	cli := srv.Client()
+	ctx := context.Background()
+	// 重複の判定はクライアントの時計(テストサーバのNow)を基準にする
+	now := time.Date(2026, 10, 16, 9, 0, 0, 0, akashi.Location)
+	srv.Now = func() time.Time { return now }
+	srv.AddStamps(testCompanyCode, testStaffID, akashi.Stamp{
+		StampedAt: akashi.NewAkTime(now.Add(-30 * time.Second)),
+		Type:      akashi.StampTypeGoToWork,
+	})
+
+	p := akashi.PostStampParam{
+		LoginCompanyCode: testCompanyCode,
+		Token:            testToken,
+		Type:             akashi.StampTypeGoToWork,
+		DedupeWindow:     time.Minute,
+	}
+	res, err := cli.PostStamp(ctx, p)
+	assert.NoError(t, err)
+	assert.True(t, res.Duplicate)
+	assert.Equal(t, testStaffID, res.StaffID)
+	assert.True(t, now.Add(-30*time.Second).Equal(res.StampedAt.Time), res.StampedAt)
+	assert.Equal(t, akashi.DedupeKey(testCompanyCode, testStaffID, akashi.StampTypeGoToWork), res.DedupeKey)
+	assert.Len(t, srv.Stamps(testCompanyCode, testStaffID), 1)
+
+	// 種別が異なる場合と期間外の場合は打刻する
+	p.Type = akashi.StampTypeBreak
+	res, err = cli.PostStamp(ctx, p)
+	assert.NoError(t, err)
+	assert.False(t, res.Duplicate)
+	assert.Equal(t, akashi.DedupeKey(testCompanyCode, testStaffID, akashi.StampTypeBreak), res.DedupeKey)
+	p.Type = akashi.StampTypeGoToWork
+	p.DedupeWindow = 10 * time.Second
+	res, err = cli.PostStamp(ctx, p)
+	assert.NoError(t, err)
+	assert.False(t, res.Duplicate)
+	assert.Len(t, srv.Stamps(testCompanyCode, testStaffID), 3)
+
+	// 過去の日時を指定した打刻はローカル打刻時刻で比較する
+	p.Type = akashi.StampTypeLeaveWork
+	p.StampedAt = akashi.NewAkTime(now.Add(-2 * time.Hour))
+	res, err = cli.PostStamp(ctx, p)
+	assert.NoError(t, err)
+	assert.False(t, res.Duplicate)
+	res, err = cli.PostStamp(ctx, p)
+	assert.NoError(t, err)
+	assert.True(t, res.Duplicate)
+	assert.Len(t, srv.Stamps(testCompanyCode, testStaffID), 4)
+	p.StampedAt = nil
+
+	p.Type = akashi.StampTypeUnknown
+	_, err = cli.PostStamp(ctx, p)
+	assert.Error(t, err)
 }
@@ -0,0 +1,472 @@
+// Package compliance 勤怠記録が労働基準法や36協定の基準を満たしているか検査する
+package compliance
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"hapoon/go-akashi/pkg/akashi"
+	"hapoon/go-akashi/pkg/akashi/attendance"
+	"hapoon/go-akashi/pkg/akashi/calendar"
+)
+
+// Severity 違反の重要度
+type Severity int
+
+const (
+	// SeverityInfo 参考情報
+	SeverityInfo Severity = iota + 1
+	// SeverityWarning 注意が必要
+	SeverityWarning
+	// SeverityError 是正が必要
+	SeverityError
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityInfo:
+		return "info"
+	case SeverityWarning:
+		return "warning"
+	case SeverityError:
+		return "error"
+	default:
+		return ""
+	}
+}
+
+// ParseSeverity 重要度の名前を解釈する
+func ParseSeverity(s string) (Severity, error) {
+	for _, v := range []Severity{SeverityInfo, SeverityWarning, SeverityError} {
+		if v.String() == s {
+			return v, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown severity %q (info, warning, error)", s)
+}
+
+// Violation ルールへの違反
+type Violation struct {
+	Rule     string    // ルール名
+	StaffID  int       // 従業員ID
+	Date     time.Time // 勤務日(週・月・年単位のルールは期間の初日)
+	Severity Severity  // 重要度
+	Reason   string    // 違反の内容
+}
+
+// Rule 1人の従業員の勤怠記録を検査するルール
+type Rule interface {
+	// Name ルール名
+	Name() string
+	// Check 勤務日順の勤怠記録を検査する
+	Check(days []attendance.Day) []Violation
+}
+
+// NewRule 関数をルールとして利用する
+func NewRule(name string, check func(days []attendance.Day) []Violation) Rule {
+	return funcRule{name: name, check: check}
+}
+
+type funcRule struct {
+	name  string
+	check func(days []attendance.Day) []Violation
+}
+
+func (r funcRule) Name() string                            { return r.name }
+func (r funcRule) Check(days []attendance.Day) []Violation { return r.check(days) }
+
+// Checker 複数のルールで勤怠記録を検査する
+type Checker struct {
+	Rules []Rule // 検査するルール
+}
+
+// NewChecker rulesで検査するCheckerを返す
+func NewChecker(rules ...Rule) *Checker {
+	return &Checker{Rules: rules}
+}
+
+// Check 従業員ごとにすべてのルールで検査する
+// 違反は従業員ID、日付、ルール名の順に並ぶ
+func (c *Checker) Check(days []attendance.Day) []Violation {
+	byStaff := map[int][]attendance.Day{}
+	var ids []int
+	for _, d := range days {
+		if _, ok := byStaff[d.StaffID]; !ok {
+			ids = append(ids, d.StaffID)
+		}
+		byStaff[d.StaffID] = append(byStaff[d.StaffID], d)
+	}
+	var violations []Violation
+	for _, id := range ids {
+		staffDays := byStaff[id]
+		sort.SliceStable(staffDays, func(i, j int) bool { return staffDays[i].Date.Before(staffDays[j].Date) })
+		for _, r := range c.Rules {
+			for _, v := range r.Check(staffDays) {
+				if v.Rule == "" {
+					v.Rule = r.Name()
+				}
+				v.StaffID = id
+				violations = append(violations, v)
+			}
+		}
+	}
+	sort.SliceStable(violations, func(i, j int) bool {
+		a, b := violations[i], violations[j]
+		if a.StaffID != b.StaffID {
+			return a.StaffID < b.StaffID
+		}
+		if !a.Date.Equal(b.Date) {
+			return a.Date.Before(b.Date)
+		}
+		return a.Rule < b.Rule
+	})
+	return violations
+}
+
+// CheckStamps GetStampsの結果をbで勤怠記録にして検査する
+func (c *Checker) CheckStamps(b attendance.Builder, res akashi.GetStampResponse) []Violation {
+	return c.Check(b.BuildResponse(res))
+}
+
+// Config 標準のルールの基準
+// 時間が0の基準は検査しない
+type Config struct {
+	DailyLimit         time.Duration      // 1日の法定労働時間
+	WeeklyLimit        time.Duration      // 1週の法定労働時間
+	WeekStart          time.Weekday       // 週の起算日
+	MonthlyOvertimeCap time.Duration      // 36協定の1か月の時間外労働の上限
+	YearlyOvertimeCap  time.Duration      // 36協定の1年の時間外労働の上限
+	YearStartMonth     time.Month         // 36協定の対象期間の起算月
+	Interval           time.Duration      // 勤務間インターバル
+	Calendar           *calendar.Calendar // 営業日の判定(nilの場合は休日の勤務を検査しない)
+}
+
+// DefaultConfig 労働基準法と36協定の原則の基準
+func DefaultConfig() Config {
+	return Config{
+		DailyLimit:         8 * time.Hour,
+		WeeklyLimit:        40 * time.Hour,
+		WeekStart:          time.Sunday,
+		MonthlyOvertimeCap: 45 * time.Hour,
+		YearlyOvertimeCap:  360 * time.Hour,
+		YearStartMonth:     time.April,
+		Interval:           11 * time.Hour,
+	}
+}
+
+// DefaultRules cの基準による標準のルール
+func DefaultRules(c Config) []Rule {
+	return []Rule{
+		BreakRule{Thresholds: DefaultBreakThresholds, Severity: SeverityError},
+		DailyOvertimeRule{Limit: c.DailyLimit, Severity: SeverityInfo},
+		WeeklyOvertimeRule{Limit: c.WeeklyLimit, WeekStart: c.WeekStart, Severity: SeverityInfo},
+		MonthlyOvertimeCapRule{Config: c, Severity: SeverityError},
+		YearlyOvertimeCapRule{Config: c, Severity: SeverityError},
+		IntervalRule{Min: c.Interval, Severity: SeverityWarning},
+		HolidayWorkRule{Calendar: c.Calendar, Severity: SeverityWarning},
+	}
+}
+
+// BreakThreshold 労働時間に対して必要な休憩時間
+type BreakThreshold struct {
+	Work  time.Duration // この労働時間を超える場合
+	Break time.Duration // 必要な休憩時間
+}
+
+// DefaultBreakThresholds 労働基準法第34条の休憩時間
+var DefaultBreakThresholds = []BreakThreshold{
+	{Work: 6 * time.Hour, Break: 45 * time.Minute},
+	{Work: 8 * time.Hour, Break: time.Hour},
+}
+
+// BreakRule 労働時間に応じた休憩を取得しているか
+type BreakRule struct {
+	Thresholds []BreakThreshold
+	Severity   Severity
+}
+
+// Name implements Rule
+func (BreakRule) Name() string { return "break" }
+
+// Check implements Rule
+func (r BreakRule) Check(days []attendance.Day) []Violation {
+	var violations []Violation
+	for _, d := range days {
+		var required time.Duration
+		for _, t := range r.Thresholds {
+			if d.Net > t.Work && t.Break > required {
+				required = t.Break
+			}
+		}
+		if d.Break < required {
+			violations = append(violations, Violation{
+				Date:     d.Date,
+				Severity: r.Severity,
+				Reason:   fmt.Sprintf("労働時間%sに対して休憩が%sです(%s以上必要)", hm(d.Net), hm(d.Break), hm(required)),
+			})
+		}
+	}
+	return violations
+}
+
+// DailyOvertimeRule 1日の労働時間が基準を超えていないか
+type DailyOvertimeRule struct {
+	Limit    time.Duration
+	Severity Severity
+}
+
+// Name implements Rule
+func (DailyOvertimeRule) Name() string { return "daily-overtime" }
+
+// Check implements Rule
+func (r DailyOvertimeRule) Check(days []attendance.Day) []Violation {
+	if r.Limit <= 0 {
+		return nil
+	}
+	var violations []Violation
+	for _, d := range days {
+		if d.Net > r.Limit {
+			violations = append(violations, Violation{
+				Date:     d.Date,
+				Severity: r.Severity,
+				Reason:   fmt.Sprintf("労働時間%sが1日の基準%sを%s超えています", hm(d.Net), hm(r.Limit), hm(d.Net-r.Limit)),
+			})
+		}
+	}
+	return violations
+}
+
+// WeeklyOvertimeRule 1週の労働時間が基準を超えていないか
+// 基準は法定の上限なので、週に休日や休業日があっても変わらない。休日の勤務はHolidayWorkRuleで検査する
+type WeeklyOvertimeRule struct {
+	Limit     time.Duration
+	WeekStart time.Weekday
+	Severity  Severity
+}
+
+// Name implements Rule
+func (WeeklyOvertimeRule) Name() string { return "weekly-overtime" }
+
+// Check implements Rule
+func (r WeeklyOvertimeRule) Check(days []attendance.Day) []Violation {
+	if r.Limit <= 0 {
+		return nil
+	}
+	var violations []Violation
+	for _, w := range groupBy(days, func(t time.Time) time.Time { return weekStart(t, r.WeekStart) }) {
+		var total time.Duration
+		for _, d := range w.days {
+			total += d.Net
+		}
+		if total > r.Limit {
+			violations = append(violations, Violation{
+				Date:     w.start,
+				Severity: r.Severity,
+				Reason:   fmt.Sprintf("週の労働時間%sが基準%sを%s超えています", hm(total), hm(r.Limit), hm(total-r.Limit)),
+			})
+		}
+	}
+	return violations
+}
+
+// MonthlyOvertimeCapRule 1か月の時間外労働が36協定の上限を超えていないか
+type MonthlyOvertimeCapRule struct {
+	Config   Config
+	Severity Severity
+}
+
+// Name implements Rule
+func (MonthlyOvertimeCapRule) Name() string { return "monthly-overtime-cap" }
+
+// Check implements Rule
+func (r MonthlyOvertimeCapRule) Check(days []attendance.Day) []Violation {
+	return overtimeCap(days, r.Config, r.Config.MonthlyOvertimeCap, r.Severity, "1か月", func(t time.Time) time.Time {
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+	})
+}
+
+// YearlyOvertimeCapRule 1年の時間外労働が36協定の上限を超えていないか
+// 与えられた勤怠記録の範囲で集計するので、対象期間の途中までの場合は途中までの累計で判定する
+type YearlyOvertimeCapRule struct {
+	Config   Config
+	Severity Severity
+}
+
+// Name implements Rule
+func (YearlyOvertimeCapRule) Name() string { return "yearly-overtime-cap" }
+
+// Check implements Rule
+func (r YearlyOvertimeCapRule) Check(days []attendance.Day) []Violation {
+	start := r.Config.YearStartMonth
+	if start == 0 {
+		start = time.January
+	}
+	return overtimeCap(days, r.Config, r.Config.YearlyOvertimeCap, r.Severity, "1年", func(t time.Time) time.Time {
+		y := t.Year()
+		if t.Month() < start {
+			y--
+		}
+		return time.Date(y, start, 1, 0, 0, 0, 0, t.Location())
+	})
+}
+
+// overtimeCap 期間ごとの時間外労働が上限を超えていないか
+func overtimeCap(days []attendance.Day, c Config, limit time.Duration, severity Severity, label string, periodStart func(time.Time) time.Time) []Violation {
+	if limit <= 0 {
+		return nil
+	}
+	ot := Overtime(days, c)
+	var totals []period
+	for i, d := range days {
+		start := periodStart(d.Date)
+		if len(totals) == 0 || !totals[len(totals)-1].start.Equal(start) {
+			totals = append(totals, period{start: start})
+		}
+		totals[len(totals)-1].overtime += ot[i]
+	}
+	var violations []Violation
+	for _, p := range totals {
+		if total := p.overtime; total > limit {
+			violations = append(violations, Violation{
+				Date:     p.start,
+				Severity: severity,
+				Reason:   fmt.Sprintf("%sの時間外労働%sが36協定の上限%sを%s超えています", label, hm(total), hm(limit), hm(total-limit)),
+			})
+		}
+	}
+	return violations
+}
+
+// Overtime 勤務日ごとの時間外労働
+//
+// 1日の基準を超えた時間と、それ以外の労働時間の週の累計が週の基準を超えた時間の合計。
+// 結果はdaysと同じ順に並ぶ
+func Overtime(days []attendance.Day, c Config) []time.Duration {
+	ot := make([]time.Duration, len(days))
+	weekly := map[time.Time]time.Duration{}
+	for i, d := range days {
+		daily := d.Net - c.DailyLimit
+		if daily < 0 || c.DailyLimit == 0 {
+			daily = 0
+		}
+		regular := d.Net - daily
+		w := weekStart(d.Date, c.WeekStart)
+		before := weekly[w]
+		weekly[w] = before + regular
+		if c.WeeklyLimit > 0 && weekly[w] > c.WeeklyLimit {
+			excess := weekly[w] - c.WeeklyLimit
+			if before > c.WeeklyLimit {
+				excess = regular
+			}
+			daily += excess
+		}
+		ot[i] = daily
+	}
+	return ot
+}
+
+// IntervalRule 退勤から次の出勤までの間隔が基準以上あるか
+type IntervalRule struct {
+	Min      time.Duration
+	Severity Severity
+}
+
+// Name implements Rule
+func (IntervalRule) Name() string { return "interval" }
+
+// Check implements Rule
+func (r IntervalRule) Check(days []attendance.Day) []Violation {
+	var violations []Violation
+	for i := 1; i < len(days); i++ {
+		out, in := days[i-1].LastOut(), days[i].FirstIn()
+		if out.IsZero() || in.IsZero() {
+			continue
+		}
+		if gap := in.Sub(out); gap < r.Min {
+			violations = append(violations, Violation{
+				Date:     days[i].Date,
+				Severity: r.Severity,
+				Reason:   fmt.Sprintf("前日の退勤から出勤までの間隔%sが基準%s未満です", hm(gap), hm(r.Min)),
+			})
+		}
+	}
+	return violations
+}
+
+// HolidayWorkRule カレンダーの休日に勤務していないか
+// Calendarがnilの場合は検査しない
+type HolidayWorkRule struct {
+	Calendar *calendar.Calendar
+	Severity Severity
+}
+
+// Name implements Rule
+func (HolidayWorkRule) Name() string { return "holiday-work" }
+
+// Check implements Rule
+func (r HolidayWorkRule) Check(days []attendance.Day) []Violation {
+	if r.Calendar == nil {
+		return nil
+	}
+	var violations []Violation
+	for _, d := range days {
+		if d.Net <= 0 || r.Calendar.IsWorkday(d.Date) {
+			continue
+		}
+		name, _ := r.Calendar.Holiday(d.Date)
+		if name == "" {
+			name = "週休日"
+		}
+		violations = append(violations, Violation{
+			Date:     d.Date,
+			Severity: r.Severity,
+			Reason:   fmt.Sprintf("休日(%s)に%s勤務しています", name, hm(d.Net)),
+		})
+	}
+	return violations
+}
+
+// period 期間ごとの勤怠記録
+type period struct {
+	start    time.Time
+	days     []attendance.Day
+	overtime time.Duration
+}
+
+// groupBy 勤務日順の勤怠記録を期間ごとに分ける
+func groupBy(days []attendance.Day, start func(time.Time) time.Time) []period {
+	var periods []period
+	for _, d := range days {
+		s := start(d.Date)
+		if len(periods) == 0 || !periods[len(periods)-1].start.Equal(s) {
+			periods = append(periods, period{start: s})
+		}
+		p := &periods[len(periods)-1]
+		p.days = append(p.days, d)
+	}
+	return periods
+}
+
+// weekStart tを含む週の起算日
+func weekStart(t time.Time, start time.Weekday) time.Time {
+	offset := (int(t.Weekday()) - int(start) + 7) % 7
+	return time.Date(t.Year(), t.Month(), t.Day()-offset, 0, 0, 0, 0, t.Location())
+}
+
+// hm 時間をX時間Y分で表す
+func hm(d time.Duration) string {
+	if d < 0 {
+		return "-" + hm(-d)
+	}
+	d = d.Round(time.Minute)
+	h := d / time.Hour
+	m := (d - h*time.Hour) / time.Minute
+	if h == 0 {
+		return fmt.Sprintf("%d分", m)
+	}
+	if m == 0 {
+		return fmt.Sprintf("%d時間", h)
+	}
+	return fmt.Sprintf("%d時間%d分", h, m)
+}
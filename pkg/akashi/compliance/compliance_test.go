@@ -0,0 +1,181 @@
+package compliance_test
+
+import (
+	"testing"
+	"time"
+
+	"hapoon/go-akashi/pkg/akashi"
+	"hapoon/go-akashi/pkg/akashi/attendance"
+	"hapoon/go-akashi/pkg/akashi/calendar"
+	"hapoon/go-akashi/pkg/akashi/compliance"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var jst = time.FixedZone("JST", 9*60*60)
+
+// workday month月day日のhour時からnet+brk時間勤務した記録
+func workday(month time.Month, day, hour int, net, brk time.Duration) attendance.Day {
+	date := time.Date(2026, month, day, 0, 0, 0, 0, jst)
+	start := date.Add(time.Duration(hour) * time.Hour)
+	return attendance.Day{
+		StaffID:  1,
+		Date:     date,
+		Sessions: []attendance.Session{{Start: start, End: start.Add(net + brk)}},
+		Gross:    net + brk,
+		Break:    brk,
+		Net:      net,
+	}
+}
+
+func rules(vs []compliance.Violation) []string {
+	var names []string
+	for _, v := range vs {
+		names = append(names, v.Rule)
+	}
+	return names
+}
+
+func TestBreakRule(t *testing.T) {
+	r := compliance.BreakRule{Thresholds: compliance.DefaultBreakThresholds, Severity: compliance.SeverityError}
+	days := []attendance.Day{
+		workday(9, 1, 9, 6*time.Hour, 0),                             // 6時間ちょうどは不要
+		workday(9, 2, 9, 6*time.Hour+time.Minute, 30*time.Minute),    // 45分必要
+		workday(9, 3, 9, 7*time.Hour, 45*time.Minute),                // OK
+		workday(9, 4, 9, 8*time.Hour+30*time.Minute, 45*time.Minute), // 60分必要
+		workday(9, 5, 9, 8*time.Hour+30*time.Minute, 60*time.Minute), // OK
+	}
+	vs := r.Check(days)
+	if assert.Len(t, vs, 2) {
+		assert.Equal(t, 2, vs[0].Date.Day())
+		assert.Equal(t, "労働時間6時間1分に対して休憩が30分です(45分以上必要)", vs[0].Reason)
+		assert.Equal(t, 4, vs[1].Date.Day())
+		assert.Equal(t, compliance.SeverityError, vs[1].Severity)
+	}
+}
+
+func TestOvertime(t *testing.T) {
+	c := compliance.DefaultConfig()
+	// 2026/09/06(日)から1週間
+	var days []attendance.Day
+	for d := 7; d <= 11; d++ {
+		days = append(days, workday(9, d, 9, 9*time.Hour, time.Hour))
+	}
+	days = append(days, workday(9, 12, 9, 6*time.Hour, time.Hour))
+	ot := compliance.Overtime(days, c)
+	// 平日は1時間ずつ、土曜日は週40時間を超える6時間
+	assert.Equal(t, []time.Duration{time.Hour, time.Hour, time.Hour, time.Hour, time.Hour, 6 * time.Hour}, ot)
+
+	vs := compliance.NewChecker(compliance.DefaultRules(c)...).Check(days)
+	assert.Equal(t, []string{
+		"weekly-overtime",
+		"daily-overtime", "daily-overtime", "daily-overtime", "daily-overtime", "daily-overtime",
+	}, rules(vs))
+	assert.Equal(t, "週の労働時間51時間が基準40時間を11時間超えています", vs[0].Reason)
+	assert.Equal(t, compliance.SeverityInfo, vs[0].Severity)
+	assert.Equal(t, 6, vs[0].Date.Day())
+}
+
+func TestOvertimeCaps(t *testing.T) {
+	c := compliance.DefaultConfig()
+	c.YearlyOvertimeCap = 50 * time.Hour
+	var days []attendance.Day
+	// 3〜5月に毎日3時間以上の時間外労働
+	for _, m := range []time.Month{time.March, time.April, time.May} {
+		for d := 1; d <= 16; d++ {
+			days = append(days, workday(m, d, 9, 11*time.Hour, time.Hour))
+		}
+	}
+	checker := compliance.NewChecker(
+		compliance.MonthlyOvertimeCapRule{Config: c, Severity: compliance.SeverityError},
+		compliance.YearlyOvertimeCapRule{Config: c, Severity: compliance.SeverityError},
+	)
+	vs := checker.Check(days)
+	want := []struct {
+		rule string
+		date time.Time
+	}{
+		// 3月は前年4月からの対象期間
+		{"yearly-overtime-cap", time.Date(2025, 4, 1, 0, 0, 0, 0, jst)},
+		{"monthly-overtime-cap", time.Date(2026, 3, 1, 0, 0, 0, 0, jst)},
+		{"monthly-overtime-cap", time.Date(2026, 4, 1, 0, 0, 0, 0, jst)},
+		{"yearly-overtime-cap", time.Date(2026, 4, 1, 0, 0, 0, 0, jst)},
+		{"monthly-overtime-cap", time.Date(2026, 5, 1, 0, 0, 0, 0, jst)},
+	}
+	if assert.Len(t, vs, len(want)) {
+		for i, w := range want {
+			assert.Equal(t, w.rule, vs[i].Rule)
+			assert.True(t, w.date.Equal(vs[i].Date), "%d: %s", i, vs[i].Date)
+		}
+		assert.Contains(t, vs[1].Reason, "1か月の時間外労働")
+	}
+}
+
+func TestIntervalRule(t *testing.T) {
+	r := compliance.IntervalRule{Min: 11 * time.Hour, Severity: compliance.SeverityWarning}
+	days := []attendance.Day{
+		workday(9, 1, 14, 9*time.Hour, time.Hour), // 24:00退勤
+		workday(9, 2, 9, 8*time.Hour, time.Hour),  // 9時間後に出勤
+		workday(9, 3, 9, 8*time.Hour, time.Hour),
+		{StaffID: 1, Date: time.Date(2026, 9, 4, 0, 0, 0, 0, jst)}, // 打刻なし
+	}
+	vs := r.Check(days)
+	if assert.Len(t, vs, 1) {
+		assert.Equal(t, 2, vs[0].Date.Day())
+		assert.Equal(t, "前日の退勤から出勤までの間隔9時間が基準11時間未満です", vs[0].Reason)
+	}
+}
+
+func TestHolidayWorkRule(t *testing.T) {
+	cal := calendar.New()
+	cal.Location = jst
+	cal.AddClosure(time.Date(2026, 8, 13, 0, 0, 0, 0, jst), "夏季休業")
+	days := []attendance.Day{
+		workday(time.August, 7, 9, 8*time.Hour, time.Hour),  // 金
+		workday(time.August, 8, 9, 4*time.Hour, 0),          // 土
+		workday(time.August, 11, 9, 8*time.Hour, time.Hour), // 山の日
+		workday(time.August, 13, 9, 2*time.Hour, 0),         // 休業日
+	}
+	vs := compliance.HolidayWorkRule{Calendar: cal, Severity: compliance.SeverityWarning}.Check(days)
+	if assert.Len(t, vs, 3) {
+		assert.Equal(t, "休日(週休日)に4時間勤務しています", vs[0].Reason)
+		assert.Equal(t, "休日(山の日)に8時間勤務しています", vs[1].Reason)
+		assert.Equal(t, "休日(夏季休業)に2時間勤務しています", vs[2].Reason)
+	}
+	assert.Empty(t, compliance.HolidayWorkRule{}.Check(days))
+}
+
+func TestCheckerCustomRule(t *testing.T) {
+	late := compliance.NewRule("late", func(days []attendance.Day) []compliance.Violation {
+		var vs []compliance.Violation
+		for _, d := range days {
+			if d.FirstIn().Hour() >= 10 {
+				vs = append(vs, compliance.Violation{Date: d.Date, Severity: compliance.SeverityWarning, Reason: "late"})
+			}
+		}
+		return vs
+	})
+	stamps := map[int][]akashi.Stamp{
+		2: {
+			{Type: akashi.StampTypeGoToWork, StampedAt: akashi.NewAkTime(time.Date(2026, 9, 1, 10, 30, 0, 0, jst))},
+			{Type: akashi.StampTypeLeaveWork, StampedAt: akashi.NewAkTime(time.Date(2026, 9, 1, 18, 0, 0, 0, jst))},
+		},
+		1: {
+			{Type: akashi.StampTypeGoToWork, StampedAt: akashi.NewAkTime(time.Date(2026, 9, 1, 9, 0, 0, 0, jst))},
+		},
+	}
+	days := attendance.Builder{Location: jst}.BuildAll(stamps)
+	vs := compliance.NewChecker(late).Check(days)
+	if assert.Len(t, vs, 1) {
+		assert.Equal(t, "late", vs[0].Rule)
+		assert.Equal(t, 2, vs[0].StaffID)
+	}
+}
+
+func TestParseSeverity(t *testing.T) {
+	s, err := compliance.ParseSeverity("warning")
+	assert.NoError(t, err)
+	assert.Equal(t, compliance.SeverityWarning, s)
+	_, err = compliance.ParseSeverity("fatal")
+	assert.Error(t, err)
+}
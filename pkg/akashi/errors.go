@@ -1,7 +1,129 @@
 package akashi
 
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
 // Error 失敗の原因となったエラーオブジェクト
 type Error struct {
 	Code    string `json:"code"`    // エラーコード
 	Message string `json:"message"` // エラーメッセージ
 }
+
+func (e Error) String() string {
+	if e.Code == "" {
+		return e.Message
+	}
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+var (
+	// ErrInvalidToken アクセストークンが不正
+	ErrInvalidToken = errors.New("akashi: invalid token")
+	// ErrTokenExpired アクセストークンの有効期限切れ
+	ErrTokenExpired = errors.New("akashi: token expired")
+	// ErrPermissionDenied 権限不足
+	ErrPermissionDenied = errors.New("akashi: permission denied")
+	// ErrRateLimited APIの利用制限超過
+	ErrRateLimited = errors.New("akashi: rate limited")
+	// ErrValidation リクエストパラメータの不備
+	ErrValidation = errors.New("akashi: validation failed")
+)
+
+// APIError AKASHI APIがエラーを返した場合のエラー
+//
+// errors.Isで ErrInvalidToken などのセンチネルと比較できる
+type APIError struct {
+	StatusCode int     // HTTPステータスコード
+	Endpoint   string  // リクエストしたエンドポイント(クエリを除く)
+	Errors     []Error // レスポンスに含まれるエラーオブジェクト
+}
+
+func (e *APIError) Error() string {
+	msgs := make([]string, 0, len(e.Errors))
+	for _, v := range e.Errors {
+		msgs = append(msgs, v.String())
+	}
+	s := fmt.Sprintf("akashi: %s: status code=%d", e.Endpoint, e.StatusCode)
+	if len(msgs) > 0 {
+		s += ": " + strings.Join(msgs, ", ")
+	}
+	return s
+}
+
+// Is errors.Is用の比較
+func (e *APIError) Is(target error) bool {
+	if target == nil {
+		return false
+	}
+	for _, k := range e.kinds() {
+		if k == target {
+			return true
+		}
+	}
+	return false
+}
+
+// errorCodes エラーコードとセンチネルの対応
+//
+// AKASHI API仕様のエラーコード一覧を確認できていないため、akashitestが返すコードを対応付けている。
+// 実際のAPIのコードが一致しない場合でもHTTPステータスで分類できるようにkindsで補う
+var errorCodes = map[string]error{
+	"INVALID_TOKEN":     ErrInvalidToken,
+	"TOKEN_EXPIRED":     ErrTokenExpired,
+	"PERMISSION_DENIED": ErrPermissionDenied,
+	"RATE_LIMITED":      ErrRateLimited,
+	"VALIDATION_ERROR":  ErrValidation,
+}
+
+// kinds エラーを分類して該当するセンチネルを返す
+//
+// エラーコードが既知の場合はその分類、そうでなければHTTPステータスで分類する。
+// 401はコードから期限切れか判断できない場合、期限切れの可能性があるのでErrInvalidTokenとErrTokenExpiredの両方に該当させる
+// (TokenSourceによる再発行を試みるため)。サーバエラー(5xx)と分類できないエラーは該当なし
+func (e *APIError) kinds() []error {
+	if e.StatusCode >= http.StatusInternalServerError {
+		return nil
+	}
+	for _, v := range e.Errors {
+		if k, ok := errorCodes[v.Code]; ok {
+			return []error{k}
+		}
+	}
+	switch e.StatusCode {
+	case http.StatusUnauthorized:
+		return []error{ErrInvalidToken, ErrTokenExpired}
+	case http.StatusForbidden:
+		return []error{ErrPermissionDenied}
+	case http.StatusTooManyRequests:
+		return []error{ErrRateLimited}
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		return []error{ErrValidation}
+	default:
+		return nil
+	}
+}
+
+// newAPIError エラーオブジェクトからAPIErrorを生成する
+func newAPIError(statusCode int, endpoint string, errs []Error) *APIError {
+	if i := strings.IndexByte(endpoint, '?'); i >= 0 {
+		endpoint = endpoint[:i]
+	}
+	return &APIError{
+		StatusCode: statusCode,
+		Endpoint:   endpoint,
+		Errors:     errs,
+	}
+}
+
+// IsNetworkError 通信の失敗でAPIの応答を受け取れなかったエラーか
+// 呼び出し元によるcontextのキャンセルは含まない。応答を受け取る前の失敗なのでリクエストが処理されている可能性はある
+func IsNetworkError(err error) bool {
+	var ue *url.Error
+	return errors.As(err, &ue) && !errors.Is(err, context.Canceled)
+}
@@ -0,0 +1,98 @@
+package akashi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPIErrorIs(t *testing.T) {
+	tests := map[string]struct {
+		err     *APIError
+		target  error
+		targets []error // 複数に該当する場合
+	}{
+		// コードがない401は期限切れの可能性もある
+		"unauthorized": {
+			err:     &APIError{StatusCode: http.StatusUnauthorized},
+			targets: []error{ErrInvalidToken, ErrTokenExpired},
+		},
+		"unauthorized unknown code": {
+			err:     &APIError{StatusCode: http.StatusUnauthorized, Errors: []Error{{Code: "E401", Message: "認証に失敗しました"}}},
+			targets: []error{ErrInvalidToken, ErrTokenExpired},
+		},
+		"invalid": {
+			err:    &APIError{StatusCode: http.StatusUnauthorized, Errors: []Error{{Code: "INVALID_TOKEN", Message: "invalid token"}}},
+			target: ErrInvalidToken,
+		},
+		"expired": {
+			err:    &APIError{StatusCode: http.StatusUnauthorized, Errors: []Error{{Code: "TOKEN_EXPIRED", Message: "token expired"}}},
+			target: ErrTokenExpired,
+		},
+		"expired(success=false)": {
+			err:    &APIError{StatusCode: http.StatusOK, Errors: []Error{{Code: "TOKEN_EXPIRED", Message: "アクセストークンの有効期限が切れています"}}},
+			target: ErrTokenExpired,
+		},
+		// メッセージの文言では分類しない
+		"forbidden mentioning expiry": {
+			err:    &APIError{StatusCode: http.StatusForbidden, Errors: []Error{{Message: "token expired"}}},
+			target: ErrPermissionDenied,
+		},
+		"server error mentioning expiry": {
+			err: &APIError{StatusCode: http.StatusInternalServerError, Errors: []Error{{Code: "TOKEN_EXPIRED", Message: "session expired"}}},
+		},
+		"forbidden": {
+			err:    &APIError{StatusCode: http.StatusForbidden},
+			target: ErrPermissionDenied,
+		},
+		"too many requests": {
+			err:    &APIError{StatusCode: http.StatusTooManyRequests},
+			target: ErrRateLimited,
+		},
+		"bad request": {
+			err:    &APIError{StatusCode: http.StatusBadRequest, Errors: []Error{{Code: "E001", Message: "invalid start_date"}}},
+			target: ErrValidation,
+		},
+		"success=false": {
+			err:    &APIError{StatusCode: http.StatusOK, Errors: []Error{{Code: "VALIDATION_ERROR", Message: "invalid type"}}},
+			target: ErrValidation,
+		},
+		"unknown success=false": {
+			err: &APIError{StatusCode: http.StatusOK, Errors: []Error{{Code: "E999", Message: "トークンを確認してください"}}},
+		},
+	}
+	sentinels := []error{ErrInvalidToken, ErrTokenExpired, ErrPermissionDenied, ErrRateLimited, ErrValidation}
+	for scenario, test := range tests {
+		targets := test.targets
+		if test.target != nil {
+			targets = append(targets, test.target)
+		}
+		for _, s := range sentinels {
+			want := false
+			for _, target := range targets {
+				want = want || s == target
+			}
+			assert.Equal(t, want, errors.Is(test.err, s), "%s: %v", scenario, s)
+		}
+	}
+}
+
+func TestIsNetworkError(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	srv.Close()
+	cli := NewClient(WithBaseURL(srv.URL))
+	_, err := cli.GetStaff(context.Background(), GetStaffParam{LoginCompanyCode: "abc", Token: "t"})
+	assert.True(t, IsNetworkError(err), err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = cli.GetStaff(ctx, GetStaffParam{LoginCompanyCode: "abc", Token: "t"})
+	assert.False(t, IsNetworkError(err), err)
+
+	assert.False(t, IsNetworkError(&APIError{StatusCode: http.StatusServiceUnavailable}))
+	assert.False(t, IsNetworkError(nil))
+}
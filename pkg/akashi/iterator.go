@@ -0,0 +1,204 @@
+package akashi
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrIteratorDone イテレータの終端を表す
+var ErrIteratorDone = errors.New("akashi: no more items in iterator")
+
+// StaffIterator 管理下にある従業員を全ページ分順に返すイテレータ
+//
+// ページは必要になった時点で取得する。WithPrefetchを指定した場合は
+// 後続のページを並行して先読みする。
+type StaffIterator struct {
+	c       *Client
+	param   GetStaffParam
+	workers int
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	buf   []Staff
+	next  int // 次に取得するページ番号
+	skip  int // 開始ページより前のページの従業員数
+	seen  int // 取得済みの従業員数
+	total int // 取得することができる従業員数
+	done  bool
+	err   error
+
+	// 先読み
+	slots []chan staffPage
+	sem   chan struct{}
+}
+
+type staffPage struct {
+	res GetStaffResponse
+	err error
+}
+
+// IteratorOption イテレータの設定
+type IteratorOption func(*StaffIterator)
+
+// WithPrefetch 最大workers件のページを並行して先読みする
+func WithPrefetch(workers int) IteratorOption {
+	return func(it *StaffIterator) {
+		it.workers = workers
+	}
+}
+
+// StaffIterator 従業員のイテレータを生成する
+// param.Pageを指定した場合はそのページから取得する。StaffIDとTargetは無視される。
+// 利用後はStopを呼び出す
+func (c *Client) StaffIterator(ctx context.Context, param GetStaffParam, opts ...IteratorOption) *StaffIterator {
+	param.StaffID = 0
+	param.Target = ""
+	if param.Page < 1 {
+		param.Page = 1
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	it := &StaffIterator{
+		c:      c,
+		param:  param,
+		ctx:    ctx,
+		cancel: cancel,
+		next:   param.Page,
+	}
+	for _, opt := range opts {
+		opt(it)
+	}
+	return it
+}
+
+// Next 次の従業員を返す
+// すべて返し終えた場合はErrIteratorDoneを返す
+func (it *StaffIterator) Next() (Staff, error) {
+	for len(it.buf) == 0 {
+		if it.err != nil {
+			return Staff{}, it.err
+		}
+		if it.done {
+			return Staff{}, ErrIteratorDone
+		}
+		if err := it.fetch(); err != nil {
+			it.err = err
+			it.cancel()
+		}
+	}
+	s := it.buf[0]
+	it.buf = it.buf[1:]
+	return s, nil
+}
+
+// TotalCount 取得することができる従業員数
+// 最初のページを取得するまでは0を返す
+func (it *StaffIterator) TotalCount() int {
+	return it.total
+}
+
+// Stop 先読みを中止してイテレータを終了する
+func (it *StaffIterator) Stop() {
+	it.cancel()
+	it.buf = nil
+	if it.err == nil {
+		it.err = ErrIteratorDone
+	}
+}
+
+// fetch 次のページを取得してバッファに追加する
+func (it *StaffIterator) fetch() error {
+	var res GetStaffResponse
+	if i := it.next - it.param.Page - 1; i >= 0 && i < len(it.slots) {
+		select {
+		case p := <-it.slots[i]:
+			<-it.sem
+			if p.err != nil {
+				return p.err
+			}
+			res = p.res
+		case <-it.ctx.Done():
+			return it.ctx.Err()
+		}
+	} else {
+		var err error
+		if res, err = it.page(it.next); err != nil {
+			return err
+		}
+	}
+	first := it.next == it.param.Page
+	if first {
+		it.skip = (it.param.Page - 1) * len(res.Staffs)
+	}
+	it.next++
+	it.buf = append(it.buf, res.Staffs...)
+	it.seen += len(res.Staffs)
+	it.total = res.TotalCount
+	if len(res.Staffs) == 0 || it.skip+it.seen >= it.total {
+		it.done = true
+		it.cancel()
+		return nil
+	}
+	if first && it.workers > 0 {
+		it.prefetch(len(res.Staffs))
+	}
+	return nil
+}
+
+// prefetch 残りのページを並行して取得する
+// 取得済みで未読のページがworkers件に達した場合は読み出されるまで待つ
+func (it *StaffIterator) prefetch(pageSize int) {
+	remaining := it.total - it.skip - it.seen
+	n := (remaining + pageSize - 1) / pageSize
+	if n <= 0 {
+		return
+	}
+	it.slots = make([]chan staffPage, n)
+	for i := range it.slots {
+		it.slots[i] = make(chan staffPage, 1)
+	}
+	it.sem = make(chan struct{}, it.workers)
+	first := it.next
+	go func() {
+		for i := range it.slots {
+			select {
+			case it.sem <- struct{}{}:
+			case <-it.ctx.Done():
+				return
+			}
+			go func(i int) {
+				res, err := it.page(first + i)
+				it.slots[i] <- staffPage{res: res, err: err}
+			}(i)
+		}
+	}()
+}
+
+func (it *StaffIterator) page(page int) (GetStaffResponse, error) {
+	p := it.param
+	p.Page = page
+	return it.c.GetStaff(it.ctx, p)
+}
+
+// ListAllStaff 管理下にある従業員を全ページ分取得する
+// DefaultClientを利用する
+func ListAllStaff(ctx context.Context, param GetStaffParam, opts ...IteratorOption) ([]Staff, error) {
+	return DefaultClient.ListAllStaff(ctx, param, opts...)
+}
+
+// ListAllStaff 管理下にある従業員を全ページ分取得する
+func (c *Client) ListAllStaff(ctx context.Context, param GetStaffParam, opts ...IteratorOption) ([]Staff, error) {
+	it := c.StaffIterator(ctx, param, opts...)
+	defer it.Stop()
+	var staffs []Staff
+	for {
+		s, err := it.Next()
+		if err == ErrIteratorDone {
+			return staffs, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		staffs = append(staffs, s)
+	}
+}
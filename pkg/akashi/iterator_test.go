@@ -0,0 +1,110 @@
+package akashi_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"hapoon/go-akashi/pkg/akashi"
+	"hapoon/go-akashi/pkg/akashi/akashitest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newStaffServer n人の従業員を登録したテスト用サーバを起動する
+func newStaffServer(n, pageSize int) *akashitest.Server {
+	srv := newTestServer()
+	srv.PageSize = pageSize
+	for i := 2; i <= n; i++ {
+		srv.AddStaff(testCompanyCode, akashi.Staff{ID: i})
+	}
+	return srv
+}
+
+func TestListAllStaff(t *testing.T) {
+	tests := map[string]struct {
+		staffs   int
+		pageSize int
+		prefetch int
+		page     int
+		want     int
+		requests int
+	}{
+		"single page":        {staffs: 3, pageSize: 10, want: 3, requests: 1},
+		"exact pages":        {staffs: 10, pageSize: 5, want: 10, requests: 2},
+		"partial last page":  {staffs: 11, pageSize: 5, want: 11, requests: 3},
+		"prefetch":           {staffs: 23, pageSize: 5, prefetch: 2, want: 23, requests: 5},
+		"prefetch(1 worker)": {staffs: 23, pageSize: 5, prefetch: 1, want: 23, requests: 5},
+		"start page":         {staffs: 11, pageSize: 5, page: 2, want: 6, requests: 2},
+		"start page(prefetch)": {
+			staffs: 23, pageSize: 5, page: 3, prefetch: 3, want: 13, requests: 3,
+		},
+	}
+	for scenario, test := range tests {
+		srv := newStaffServer(test.staffs, test.pageSize)
+		param := akashi.GetStaffParam{LoginCompanyCode: testCompanyCode, Token: testToken, Page: test.page}
+		staffs, err := srv.Client().ListAllStaff(context.Background(), param, akashi.WithPrefetch(test.prefetch))
+		assert.NoError(t, err, scenario)
+		if assert.Len(t, staffs, test.want, scenario) {
+			// ページの順序が保たれている
+			start := 1
+			if test.page > 1 {
+				start = (test.page-1)*test.pageSize + 1
+			}
+			for i, s := range staffs {
+				assert.Equal(t, start+i, s.ID, scenario)
+			}
+		}
+		assert.Len(t, srv.Requests(), test.requests, scenario)
+		srv.Close()
+	}
+}
+
+func TestStaffIteratorError(t *testing.T) {
+	srv := newStaffServer(12, 5)
+	defer srv.Close()
+	srv.InjectFault(akashitest.Fault{Kind: akashitest.FaultServerError, Path: "/abc/staffs", Times: 1})
+
+	it := srv.Client().StaffIterator(context.Background(), akashi.GetStaffParam{LoginCompanyCode: testCompanyCode, Token: testToken})
+	defer it.Stop()
+	_, err := it.Next()
+	var apiErr *akashi.APIError
+	assert.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, http.StatusInternalServerError, apiErr.StatusCode)
+	// エラーの後は同じエラーを返し続ける
+	_, err2 := it.Next()
+	assert.Equal(t, err, err2)
+}
+
+func TestStaffIteratorCancel(t *testing.T) {
+	srv := newStaffServer(30, 5)
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	it := srv.Client().StaffIterator(ctx, akashi.GetStaffParam{LoginCompanyCode: testCompanyCode, Token: testToken}, akashi.WithPrefetch(2))
+	defer it.Stop()
+	for i := 0; i < 5; i++ {
+		_, err := it.Next()
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, 30, it.TotalCount())
+	cancel()
+	var err error
+	for err == nil {
+		_, err = it.Next()
+	}
+	assert.True(t, errors.Is(err, context.Canceled), err)
+}
+
+func TestStaffIteratorStop(t *testing.T) {
+	srv := newStaffServer(10, 5)
+	defer srv.Close()
+
+	it := srv.Client().StaffIterator(context.Background(), akashi.GetStaffParam{LoginCompanyCode: testCompanyCode, Token: testToken})
+	_, err := it.Next()
+	assert.NoError(t, err)
+	it.Stop()
+	_, err = it.Next()
+	assert.Equal(t, akashi.ErrIteratorDone, err)
+}
@@ -0,0 +1,134 @@
+package akashi
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Level ログレベル(log/slogと同じ値)
+type Level int
+
+const (
+	// LevelDebug デバッグ
+	LevelDebug Level = -4
+	// LevelInfo 情報
+	LevelInfo Level = 0
+	// LevelWarn 警告
+	LevelWarn Level = 4
+	// LevelError エラー
+	LevelError Level = 8
+)
+
+func (l Level) String() string {
+	switch {
+	case l < LevelInfo:
+		return "DEBUG"
+	case l < LevelWarn:
+		return "INFO"
+	case l < LevelError:
+		return "WARN"
+	default:
+		return "ERROR"
+	}
+}
+
+// Logger クライアントのログ出力先
+//
+// keysAndValuesはキーと値を交互に並べたもの。
+// 渡されるURLやボディのアクセストークンはマスク済み
+type Logger interface {
+	Log(level Level, msg string, keysAndValues ...interface{})
+}
+
+// nopLogger 何も出力しないLogger
+type nopLogger struct{}
+
+func (nopLogger) Log(Level, string, ...interface{}) {}
+
+// stdLogger log.Loggerに出力するLogger
+type stdLogger struct {
+	l   *log.Logger
+	min Level
+}
+
+// NewStdLogger minLevel以上のログをlに出力するLoggerを生成する
+func NewStdLogger(l *log.Logger, minLevel Level) Logger {
+	return &stdLogger{l: l, min: minLevel}
+}
+
+func (s *stdLogger) Log(level Level, msg string, keysAndValues ...interface{}) {
+	if level < s.min {
+		return
+	}
+	var b strings.Builder
+	b.WriteString(level.String())
+	b.WriteByte(' ')
+	b.WriteString(msg)
+	for i := 0; i < len(keysAndValues); i += 2 {
+		b.WriteByte(' ')
+		if i+1 < len(keysAndValues) {
+			fmt.Fprintf(&b, "%v=%v", keysAndValues[i], keysAndValues[i+1])
+		} else {
+			fmt.Fprintf(&b, "%v", keysAndValues[i])
+		}
+	}
+	s.l.Println(b.String())
+}
+
+const redacted = "REDACTED"
+
+// secretParams 値をマスクするクエリパラメータ・JSONキー
+var secretParams = []string{"token", "target"}
+
+// secretHeaders 値をマスクするヘッダ
+var secretHeaders = []string{"Authorization", "Cookie", "Set-Cookie", "X-Token"}
+
+var secretBodyPattern = regexp.MustCompile(`("(?:` + strings.Join(secretParams, "|") + `)"\s*:\s*)"(?:[^"\\]|\\.)*"`)
+
+// secretQueryPattern URLとして解析できない文字列でマスクするクエリパラメータ
+var secretQueryPattern = regexp.MustCompile(`((?:^|[?&;])(?:` + strings.Join(secretParams, "|") + `)=)[^&;#]*`)
+
+// RedactURL URLに含まれるアクセストークンをマスクする
+// URLとして解析できない場合はパラメータ名で探してマスクする
+func RedactURL(s string) string {
+	u, err := url.Parse(s)
+	if err != nil {
+		return secretQueryPattern.ReplaceAllString(s, "${1}"+redacted)
+	}
+	q, err := url.ParseQuery(u.RawQuery)
+	if err != nil {
+		return secretQueryPattern.ReplaceAllString(s, "${1}"+redacted)
+	}
+	changed := false
+	for _, k := range secretParams {
+		if _, ok := q[k]; ok {
+			q.Set(k, redacted)
+			changed = true
+		}
+	}
+	if !changed {
+		return s
+	}
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+// RedactHeader 認証情報を含むヘッダをマスクしたコピーを返す
+func RedactHeader(h http.Header) http.Header {
+	c := h.Clone()
+	for _, k := range secretHeaders {
+		if c.Get(k) != "" {
+			c.Set(k, redacted)
+		}
+	}
+	return c
+}
+
+// RedactBody JSONボディに含まれるアクセストークンをマスクする
+func RedactBody(b []byte) string {
+	return secretBodyPattern.ReplaceAllString(string(b), `$1"`+redacted+`"`)
+}
@@ -0,0 +1,82 @@
+package akashi
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedactURL(t *testing.T) {
+	tests := map[string]struct {
+		in   string
+		want string
+	}{
+		"no query":  {in: "https://example.com/abc/alerts", want: "https://example.com/abc/alerts"},
+		"token":     {in: "https://example.com/abc/alerts?token=secret", want: "https://example.com/abc/alerts?token=REDACTED"},
+		"target":    {in: "/abc/staffs?page=2&target=secret&token=secret", want: "/abc/staffs?page=2&target=REDACTED&token=REDACTED"},
+		"unrelated": {in: "/abc/stamps?start_date=20260901000000", want: "/abc/stamps?start_date=20260901000000"},
+		// URLとして解析できない場合もマスクする
+		"invalid url":    {in: "http://[::1%zz]/abc/alerts?token=secret&page=1", want: "http://[::1%zz]/abc/alerts?token=REDACTED&page=1"},
+		"invalid escape": {in: "/abc/staffs?target=a%zz&token=secret", want: "/abc/staffs?target=REDACTED&token=REDACTED"},
+	}
+	for scenario, test := range tests {
+		assert.Equal(t, test.want, RedactURL(test.in), scenario)
+	}
+}
+
+func TestRedactBody(t *testing.T) {
+	tests := map[string]struct {
+		in   string
+		want string
+	}{
+		"token":   {in: `{"token":"secret","type":11}`, want: `{"token":"REDACTED","type":11}`},
+		"spaces":  {in: `{"token" : "se\"cret"}`, want: `{"token" : "REDACTED"}`},
+		"nothing": {in: `{"type":11}`, want: `{"type":11}`},
+	}
+	for scenario, test := range tests {
+		assert.Equal(t, test.want, RedactBody([]byte(test.in)), scenario)
+	}
+}
+
+func TestRedactHeader(t *testing.T) {
+	h := http.Header{}
+	h.Set("Authorization", "Bearer secret")
+	h.Set("Content-Type", "application/json")
+	r := RedactHeader(h)
+	assert.Equal(t, "REDACTED", r.Get("Authorization"))
+	assert.Equal(t, "application/json", r.Get("Content-Type"))
+	assert.Equal(t, "Bearer secret", h.Get("Authorization"))
+}
+
+func TestClientLogging(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"success":true,"response":{}}`))
+	}))
+	defer srv.Close()
+
+	var buf bytes.Buffer
+	cli := NewClient(WithBaseURL(srv.URL), WithLogger(NewStdLogger(log.New(&buf, "", 0), LevelDebug)))
+	ctx := context.Background()
+	_, err := cli.GetStaff(ctx, GetStaffParam{LoginCompanyCode: "abc", Token: "secret-token", Target: "secret-target"})
+	assert.NoError(t, err)
+	_, err = cli.PostStamp(ctx, PostStampParam{LoginCompanyCode: "abc", Token: "secret-token"})
+	assert.NoError(t, err)
+
+	out := buf.String()
+	assert.Contains(t, out, "DEBUG sending request method=GET")
+	assert.Contains(t, out, "DEBUG sending request method=POST")
+	assert.NotContains(t, out, "secret")
+
+	// 接続エラーのメッセージにもトークンが含まれない
+	srv.Close()
+	_, err = cli.GetAlerts(ctx, GetAlertParam{LoginCompanyCode: "abc", Token: "secret-token"})
+	if assert.Error(t, err) {
+		assert.NotContains(t, err.Error(), "secret")
+	}
+	assert.NotContains(t, buf.String(), "secret")
+}
@@ -0,0 +1,102 @@
+package akashi
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// RateLimiter トークンバケット方式のリクエスト数制限
+//
+// 複数のgoroutineやクライアントから同時に利用できる
+type RateLimiter struct {
+	mu     sync.Mutex
+	rate   float64 // 1秒あたりに補充されるトークン数
+	burst  float64 // バケットの容量
+	tokens float64 // 残りトークン数(予約済みの場合は負になる)
+	last   time.Time
+}
+
+// NewRateLimiter 1秒あたりrps回、最大burst回まで連続でリクエストできるRateLimiterを生成する
+func NewRateLimiter(rps float64, burst int) *RateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &RateLimiter{
+		rate:   rps,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// Wait リクエスト可能になるまで待機する
+// 待機中にcontextが終了した場合は予約を取り消してエラーを返す
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	if l == nil || l.rate <= 0 {
+		return ctx.Err()
+	}
+	l.mu.Lock()
+	now := time.Now()
+	l.advance(now)
+	l.tokens--
+	var wait time.Duration
+	if l.tokens < 0 {
+		wait = time.Duration(-l.tokens / l.rate * float64(time.Second))
+	}
+	l.mu.Unlock()
+
+	if wait == 0 {
+		return nil
+	}
+	if deadline, ok := ctx.Deadline(); ok && deadline.Before(now.Add(wait)) {
+		l.cancel()
+		return context.DeadlineExceeded
+	}
+	if err := sleep(ctx, wait); err != nil {
+		l.cancel()
+		return err
+	}
+	return nil
+}
+
+// advance 経過時間分のトークンを補充する
+func (l *RateLimiter) advance(now time.Time) {
+	elapsed := now.Sub(l.last)
+	if elapsed <= 0 {
+		return
+	}
+	l.last = now
+	l.tokens += elapsed.Seconds() * l.rate
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+}
+
+// cancel 予約したトークンを返却する
+func (l *RateLimiter) cancel() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.tokens++
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+}
+
+var (
+	sharedLimitersMu sync.Mutex
+	sharedLimiters   = map[string]*RateLimiter{}
+)
+
+// SharedRateLimiter 企業IDごとにプロセス内で共有されるRateLimiterを返す
+// 既に生成済みの場合はrpsとburstは無視される
+func SharedRateLimiter(companyCode string, rps float64, burst int) *RateLimiter {
+	sharedLimitersMu.Lock()
+	defer sharedLimitersMu.Unlock()
+	if l, ok := sharedLimiters[companyCode]; ok {
+		return l
+	}
+	l := NewRateLimiter(rps, burst)
+	sharedLimiters[companyCode] = l
+	return l
+}
@@ -0,0 +1,54 @@
+package akashi
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimiterWait(t *testing.T) {
+	l := NewRateLimiter(50, 2)
+	ctx := context.Background()
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, l.Wait(ctx))
+		}()
+	}
+	wg.Wait()
+	// burst分の2回は即時、残り4回は20ms間隔
+	assert.True(t, time.Since(start) >= 70*time.Millisecond, time.Since(start))
+}
+
+func TestRateLimiterWaitCanceled(t *testing.T) {
+	l := NewRateLimiter(1, 1)
+	assert.NoError(t, l.Wait(context.Background()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.Error(t, l.Wait(ctx))
+
+	// 取り消した予約は返却されている
+	l.mu.Lock()
+	assert.True(t, l.tokens > -1)
+	l.mu.Unlock()
+}
+
+func TestRateLimiterNil(t *testing.T) {
+	var l *RateLimiter
+	assert.NoError(t, l.Wait(context.Background()))
+}
+
+func TestSharedRateLimiter(t *testing.T) {
+	a := SharedRateLimiter("shared-test", 10, 1)
+	b := SharedRateLimiter("shared-test", 20, 5)
+	c := SharedRateLimiter("other-test", 10, 1)
+	assert.True(t, a == b)
+	assert.False(t, a == c)
+}
@@ -0,0 +1,119 @@
+package akashi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+)
+
+const (
+	// maxResponseSize レスポンスボディの読み込み上限
+	maxResponseSize = 10 << 20
+	// maxDrainSize コネクション再利用のために読み捨てるボディの上限
+	maxDrainSize = 64 << 10
+)
+
+// request APIリクエスト
+type request struct {
+	method string
+	path   string     // クエリを含まないパス
+	query  url.Values // tokenを除くクエリ(GETのみ)
+	body   tokenBody  // リクエストボディ(POSTのみ)
+	token  string     // アクセストークン
+	// POSTを再送しても安全な場合はtrue
+	idempotent bool
+	// TokenSourceを利用せずtokenをそのまま利用する場合はtrue
+	noTokenSource bool
+}
+
+// tokenBody アクセストークンを含むリクエストボディ
+type tokenBody interface {
+	withToken(token string) interface{}
+}
+
+// envelope 全APIに共通のレスポンス形式
+type envelope struct {
+	Success  bool            `json:"success"`
+	Response json.RawMessage `json:"response"`
+	Errors   []Error         `json:"errors"`
+}
+
+// call リクエストを送信してレスポンスのresponseをoutにデコードする
+//
+// ステータスコードが200以外の場合やsuccessがfalseの場合は*APIErrorを返す
+func (c *Client) call(ctx context.Context, r *request, out interface{}) error {
+	if c.tokens == nil || r.noTokenSource {
+		return c.callWithToken(ctx, r, r.token, out)
+	}
+	token, err := c.tokens.Token(ctx)
+	if err != nil {
+		return err
+	}
+	err = c.callWithToken(ctx, r, token, out)
+	if !errors.Is(err, ErrTokenExpired) {
+		return err
+	}
+	c.logger.Log(LevelInfo, "token expired, refreshing", "path", r.path)
+	if token, err = c.tokens.Refresh(ctx, token); err != nil {
+		return err
+	}
+	return c.callWithToken(ctx, r, token, out)
+}
+
+// callWithToken tokenを利用してリクエストを送信する
+func (c *Client) callWithToken(ctx context.Context, r *request, token string, out interface{}) error {
+	var res *http.Response
+	var err error
+	switch r.method {
+	case http.MethodGet:
+		q := url.Values{}
+		for k, v := range r.query {
+			q[k] = v
+		}
+		q.Set("token", token)
+		res, err = c.Get(ctx, r.path+"?"+q.Encode())
+	case http.MethodPost:
+		var body interface{}
+		if r.body != nil {
+			body = r.body.withToken(token)
+		}
+		res, err = c.post(ctx, r.path, body, r.idempotent)
+	default:
+		return fmt.Errorf("akashi: unsupported method %s", r.method)
+	}
+	if err != nil {
+		return err
+	}
+	defer closeBody(res.Body)
+
+	var env envelope
+	decErr := json.NewDecoder(io.LimitReader(res.Body, maxResponseSize)).Decode(&env)
+	if res.StatusCode != http.StatusOK {
+		// エラーレスポンスがJSONでない場合もあるのでデコードの失敗は無視する
+		return newAPIError(res.StatusCode, r.path, env.Errors)
+	}
+	if decErr != nil {
+		return fmt.Errorf("akashi: %s: decoding response: %w", r.path, decErr)
+	}
+	if !env.Success {
+		return newAPIError(res.StatusCode, r.path, env.Errors)
+	}
+	if out == nil || len(env.Response) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(env.Response, out); err != nil {
+		return fmt.Errorf("akashi: %s: decoding response: %w", r.path, err)
+	}
+	return nil
+}
+
+// closeBody 残りのボディを読み捨ててから閉じる
+func closeBody(body io.ReadCloser) {
+	_, _ = io.Copy(ioutil.Discard, io.LimitReader(body, maxDrainSize))
+	_ = body.Close()
+}
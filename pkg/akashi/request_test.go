@@ -0,0 +1,104 @@
+package akashi
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// trackingBody Closeが呼ばれたかを記録する
+type trackingBody struct {
+	io.Reader
+	closed bool
+}
+
+func (b *trackingBody) Close() error {
+	b.closed = true
+	return nil
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestClientCall(t *testing.T) {
+	tests := map[string]struct {
+		statusCode int
+		body       string
+		want       GetAlertResponse
+		err        bool
+		apiErr     bool
+	}{
+		"ok": {
+			statusCode: http.StatusOK,
+			body:       `{"success":true,"response":{"login_company_code":"abc","staff_id":1,"count":0,"alerts":[]}}`,
+			want:       GetAlertResponse{LoginCompanyCode: "abc", StaffID: 1, Alerts: []Alert{}},
+		},
+		"success=false": {
+			statusCode: http.StatusOK,
+			body:       `{"success":false,"errors":[{"code":"E1","message":"bad"}]}`,
+			err:        true,
+			apiErr:     true,
+		},
+		"internal server error": {
+			statusCode: http.StatusInternalServerError,
+			body:       `<html>error</html>`,
+			err:        true,
+			apiErr:     true,
+		},
+		"malformed json": {
+			statusCode: http.StatusOK,
+			body:       `{"success":true,`,
+			err:        true,
+		},
+	}
+
+	for scenario, test := range tests {
+		body := &trackingBody{Reader: strings.NewReader(test.body)}
+		var query string
+		hc := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			query = req.URL.RawQuery
+			return &http.Response{StatusCode: test.statusCode, Body: body, Header: http.Header{}}, nil
+		})}
+		cli := NewClient(WithHTTPClient(hc), WithLogger(nil))
+		res, err := cli.GetAlerts(context.Background(), GetAlertParam{LoginCompanyCode: "abc", Token: "t"})
+		assert.True(t, body.closed, scenario)
+		assert.Equal(t, "token=t", query, scenario)
+		if test.err {
+			assert.Error(t, err, scenario)
+			var apiErr *APIError
+			assert.Equal(t, test.apiErr, errors.As(err, &apiErr), scenario)
+			if test.apiErr {
+				assert.Equal(t, test.statusCode, apiErr.StatusCode, scenario)
+			}
+		} else {
+			assert.NoError(t, err, scenario)
+			assert.Equal(t, test.want, res, scenario)
+		}
+	}
+}
+
+func TestClientCallPostBody(t *testing.T) {
+	var got string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b := new(strings.Builder)
+		io.Copy(b, r.Body)
+		got = b.String()
+		w.Write([]byte(`{"success":true,"response":{"login_company_code":"abc","staff_id":1,"type":11}}`))
+	}))
+	defer srv.Close()
+
+	cli := NewClient(WithBaseURL(srv.URL), WithLogger(nil))
+	res, err := cli.PostStamp(context.Background(), PostStampParam{LoginCompanyCode: "abc", Token: "t", Type: StampTypeGoToWork})
+	assert.NoError(t, err)
+	assert.Equal(t, StampTypeGoToWork, res.Type)
+	assert.JSONEq(t, `{"token":"t","type":11}`, got)
+}
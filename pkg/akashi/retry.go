@@ -0,0 +1,103 @@
+package akashi
+
+import (
+	"context"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy リトライポリシー
+type RetryPolicy struct {
+	MaxAttempts int           // 最大試行回数(1以下の場合はリトライしない)
+	BaseDelay   time.Duration // 初回リトライまでの待機時間
+	MaxDelay    time.Duration // 待機時間の上限(Retry-Afterがこれを超える場合はリトライしない)
+}
+
+// DefaultRetryPolicy 推奨のリトライポリシー
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   500 * time.Millisecond,
+	MaxDelay:    30 * time.Second,
+}
+
+// delay attempt回目の失敗後に待機する時間を返す
+// Retry-Afterの指定がMaxDelayを超える場合はfalseを返す
+func (p RetryPolicy) delay(attempt int, res *http.Response) (time.Duration, bool) {
+	maxDelay := p.MaxDelay
+	if maxDelay <= 0 {
+		maxDelay = DefaultRetryPolicy.MaxDelay
+	}
+	if res != nil {
+		if d, ok := parseRetryAfter(res.Header.Get("Retry-After"), time.Now()); ok {
+			return d, d <= maxDelay
+		}
+	}
+	base := p.BaseDelay
+	if base <= 0 {
+		base = DefaultRetryPolicy.BaseDelay
+	}
+	d := base
+	for i := 1; i < attempt && d < maxDelay; i++ {
+		d *= 2
+	}
+	if d > maxDelay {
+		d = maxDelay
+	}
+	// 同時に失敗したクライアントが一斉に再送しないように[d/2, d]の範囲でばらつかせる
+	half := int64(d / 2)
+	return time.Duration(half + rand.Int63n(half+1)), true
+}
+
+// shouldRetry 再送すべき失敗かを判定する
+func shouldRetry(ctx context.Context, res *http.Response, err error) bool {
+	if err != nil {
+		// 呼び出し元のcontextが終了している場合は再送しない
+		return ctx.Err() == nil
+	}
+	switch res.StatusCode {
+	case http.StatusTooManyRequests,
+		http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+// parseRetryAfter Retry-Afterヘッダ(秒数またはHTTP日付)を解析する
+func parseRetryAfter(v string, now time.Time) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+	if sec, err := strconv.Atoi(v); err == nil {
+		if sec < 0 {
+			return 0, false
+		}
+		return time.Duration(sec) * time.Second, true
+	}
+	t, err := http.ParseTime(v)
+	if err != nil {
+		return 0, false
+	}
+	d := t.Sub(now)
+	if d < 0 {
+		d = 0
+	}
+	return d, true
+}
+
+// sleep contextが終了するまでの間dだけ待機する
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
@@ -0,0 +1,44 @@
+package akashi
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2026, 9, 1, 9, 0, 0, 0, time.UTC)
+	tests := map[string]struct {
+		value string
+		want  time.Duration
+		ok    bool
+	}{
+		"empty":     {value: "", ok: false},
+		"seconds":   {value: "3", want: 3 * time.Second, ok: true},
+		"negative":  {value: "-1", ok: false},
+		"http date": {value: now.Add(5 * time.Second).Format(http.TimeFormat), want: 5 * time.Second, ok: true},
+		"past date": {value: now.Add(-5 * time.Second).Format(http.TimeFormat), want: 0, ok: true},
+		"invalid":   {value: "soon", ok: false},
+	}
+	for scenario, test := range tests {
+		d, ok := parseRetryAfter(test.value, now)
+		assert.Equal(t, test.ok, ok, scenario)
+		assert.Equal(t, test.want, d, scenario)
+	}
+}
+
+func TestRetryPolicyDelay(t *testing.T) {
+	p := RetryPolicy{MaxAttempts: 5, BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	for attempt, upper := range []time.Duration{100, 200, 400, 800, 1000, 1000} {
+		upper *= time.Millisecond
+		d, ok := p.delay(attempt+1, nil)
+		assert.True(t, ok)
+		assert.True(t, d >= upper/2 && d <= upper, "attempt %d: %v", attempt+1, d)
+	}
+
+	res := &http.Response{Header: http.Header{"Retry-After": []string{"2"}}}
+	_, ok := p.delay(1, res)
+	assert.False(t, ok, "Retry-After exceeds MaxDelay")
+}
@@ -2,10 +2,8 @@ package akashi
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
 	"net/http"
 	"net/url"
 	"strconv"
@@ -61,50 +59,35 @@ type GetStaffResponse struct {
 }
 
 // GetStaff fetch staff information
+// DefaultClientを利用する
 func GetStaff(ctx context.Context, param GetStaffParam) (GetStaffResponse, error) {
-	log.Println("Get staff information")
+	return DefaultClient.GetStaff(ctx, param)
+}
+
+// GetStaff fetch staff information
+func (c *Client) GetStaff(ctx context.Context, param GetStaffParam) (GetStaffResponse, error) {
 	if param.LoginCompanyCode == "" {
 		return GetStaffResponse{}, errors.New("LoginCompanyCode must be set")
 	}
-	if param.Token == "" {
+	if param.Token == "" && c.tokens == nil {
 		return GetStaffResponse{}, errors.New("Token must be set")
 	}
-	endpoint := fmt.Sprintf("/%s/staffs", param.LoginCompanyCode)
+	path := fmt.Sprintf("/%s/staffs", param.LoginCompanyCode)
 	if param.StaffID != 0 {
-		log.Println("staff ID:", param.StaffID)
-		endpoint += fmt.Sprintf("/%d", param.StaffID)
+		path += fmt.Sprintf("/%d", param.StaffID)
 	}
 	uv := url.Values{}
-	uv.Add("token", param.Token)
 	if param.Target != "" {
 		uv.Add("target", param.Target)
 	}
 	if param.Page != 0 {
 		uv.Add("page", strconv.FormatInt(int64(param.Page), 10))
 	}
-	q := uv.Encode()
-	if q != "" {
-		endpoint += "?" + q
-	}
 
-	cli := NewClient(param.LoginCompanyCode, param.Token)
-	res, err := cli.Get(ctx, endpoint)
-	if err != nil {
+	var res GetStaffResponse
+	r := &request{method: http.MethodGet, path: path, query: uv, token: param.Token}
+	if err := c.call(ctx, r, &res); err != nil {
 		return GetStaffResponse{}, err
 	}
-	if res.StatusCode != http.StatusOK {
-		return GetStaffResponse{}, fmt.Errorf("Status code=%d", res.StatusCode)
-	}
-	var gsr struct {
-		Success  bool             `json:"success"`
-		Response GetStaffResponse `json:"response"`
-		Errors   []Error          `json:"errors"`
-	}
-	if err := json.NewDecoder(res.Body).Decode(&gsr); err != nil {
-		return GetStaffResponse{}, err
-	}
-	if !gsr.Success {
-		return GetStaffResponse{}, errors.New("Requesting Staff API failed")
-	}
-	return gsr.Response, nil
+	return res, nil
 }
@@ -2,7 +2,6 @@ package akashi
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
@@ -10,13 +9,6 @@ import (
 	"time"
 )
 
-var (
-	// DateFormat yyyymmddHHMMSS形式
-	DateFormat = "20060102150405"
-	// ReturnDateFormat yyyy/mm/dd HH:MM:SS形式
-	ReturnDateFormat = "2006/01/02 15:04:05"
-)
-
 // Stamp 打刻データ
 type Stamp struct {
 	StampedAt  *AkTime        `json:"stamped_at"` // 打刻日時
@@ -26,6 +18,19 @@ type Stamp struct {
 	Attributes StampAttribute `json:"attributes"` // 実績参照結果
 }
 
+// ClientTime 打刻した時刻
+// ローカル打刻時刻があればその時刻、なければサーバ側での打刻日時。どちらもない場合はゼロ値
+func (s Stamp) ClientTime() time.Time {
+	switch {
+	case s.LocalTime != nil && !s.LocalTime.IsZero():
+		return s.LocalTime.Time
+	case s.StampedAt != nil:
+		return s.StampedAt.Time
+	default:
+		return time.Time{}
+	}
+}
+
 // StampType 打刻種別
 type StampType int
 
@@ -75,21 +80,6 @@ type StampAttribute struct {
 	IP          string  `json:"ip"`           // 打刻機のIPアドレス
 }
 
-// AkTime 時間
-type AkTime struct {
-	time.Time
-}
-
-// UnmarshalJSON time.UUnmarshalJSONの拡張
-func (a *AkTime) UnmarshalJSON(data []byte) error {
-	if string(data) == "null" {
-		return nil
-	}
-	t, err := time.Parse(`"`+ReturnDateFormat+`"`, string(data))
-	*a = AkTime{t}
-	return err
-}
-
 // GetStampParam 打刻情報取得リクエストパラメータ
 type GetStampParam struct {
 	LoginCompanyCode string    // AKASHI企業ID
@@ -108,11 +98,17 @@ type GetStampResponse struct {
 }
 
 // GetStamps 打刻情報取得
+// DefaultClientを利用する
 func GetStamps(ctx context.Context, param GetStampParam) (GetStampResponse, error) {
+	return DefaultClient.GetStamps(ctx, param)
+}
+
+// GetStamps 打刻情報取得
+func (c *Client) GetStamps(ctx context.Context, param GetStampParam) (GetStampResponse, error) {
 	if param.LoginCompanyCode == "" {
 		return GetStampResponse{}, errors.New("LoginCompanyCode must be set")
 	}
-	if param.Token == "" {
+	if param.Token == "" && c.tokens == nil {
 		return GetStampResponse{}, errors.New("Token must be set")
 	}
 	if param.StartDate.IsZero() {
@@ -122,49 +118,33 @@ func GetStamps(ctx context.Context, param GetStampParam) (GetStampResponse, erro
 		return GetStampResponse{}, errors.New("EndDate must be set")
 	}
 
-	endpoint := fmt.Sprintf("/%s/stamps", param.LoginCompanyCode)
+	path := fmt.Sprintf("/%s/stamps", param.LoginCompanyCode)
 	if param.StaffID != 0 {
-		endpoint = fmt.Sprintf("%s/%d", endpoint, param.StaffID)
+		path = fmt.Sprintf("%s/%d", path, param.StaffID)
 	}
 	uv := url.Values{}
-	uv.Add("token", param.Token)
-	uv.Add("start_date", param.StartDate.Format(DateFormat))
-	uv.Add("end_date", param.EndDate.Format(DateFormat))
-	q := uv.Encode()
-	if q != "" {
-		endpoint += "?" + q
-	}
-
-	cli := NewClient(param.LoginCompanyCode, param.Token)
-	res, err := cli.Get(ctx, endpoint)
-	if err != nil {
-		return GetStampResponse{}, err
-	}
-	if res.StatusCode != http.StatusOK {
-		return GetStampResponse{}, fmt.Errorf("Status code=%d", res.StatusCode)
-	}
-	var gsr struct {
-		Success  bool             `json:"success"`
-		Response GetStampResponse `json:"response"`
-		Errors   []Error          `json:"errors"`
-	}
+	uv.Add("start_date", param.StartDate.In(location()).Format(DateFormat))
+	uv.Add("end_date", param.EndDate.In(location()).Format(DateFormat))
 
-	if err := json.NewDecoder(res.Body).Decode(&gsr); err != nil {
+	var res GetStampResponse
+	r := &request{method: http.MethodGet, path: path, query: uv, token: param.Token}
+	if err := c.call(ctx, r, &res); err != nil {
 		return GetStampResponse{}, err
 	}
-	if !gsr.Success {
-		return GetStampResponse{}, errors.New("Requesting Stamps API failed")
-	}
-	return gsr.Response, nil
+	return res, nil
 }
 
 // PostStampParam 打刻リクエストパラメータ
 type PostStampParam struct {
-	LoginCompanyCode string    // AKASHI企業ID
+	LoginCompanyCode string    `json:"-"`                   // AKASHI企業ID
 	Token            string    `json:"token"`               // アクセストークン
 	Type             StampType `json:"type,omitempty"`      // 打刻種別
 	StampedAt        *AkTime   `json:"stampedAt,omitempty"` // クライアントでの打刻日時
-	Timezone         string    `json:"timezone,omitempty"`  // クライアントでのタイムゾーン
+	Timezone         string    `json:"timezone,omitempty"`  // クライアントでのタイムゾーン(±HH:MM形式)
+	Retryable        bool      `json:"-"`                   // 通信失敗時の再送を許可する(重複打刻にならないと保証できる場合のみ)
+	// DedupeWindow 0より大きい場合は打刻の前にGetStampsで前後DedupeWindow以内の打刻を確認し、
+	// 同じ種別の打刻があれば打刻せずにその打刻を返す(Typeの指定が必要)
+	DedupeWindow time.Duration `json:"-"`
 }
 
 // PostStampResponse 打刻レスポンス
@@ -173,39 +153,124 @@ type PostStampResponse struct {
 	StaffID          int       `json:"staff_id"`           // 従業員ID
 	Type             StampType `json:"type"`               // 打刻種別
 	StampedAt        *AkTime   `json:"stampedAt"`          // サーバ側での打刻日時
+	Duplicate        bool      `json:"-"`                  // DedupeWindow以内に同じ打刻があったため打刻しなかった
+	DedupeKey        string    `json:"-"`                  // 重複を判定したキー(DedupeWindowの指定がある場合のみ)
+}
+
+// DedupeKey 重複打刻を判定するクライアント側のキー
+// 企業・従業員・打刻種別が同じ打刻は同じキーになり、同じキーの打刻が一定時間内にあれば重複とみなす
+func DedupeKey(companyCode string, staffID int, typ StampType) string {
+	return fmt.Sprintf("%s/%d/%d", companyCode, staffID, typ)
 }
 
 // PostStamp 打刻
+// DefaultClientを利用する
 func PostStamp(ctx context.Context, param PostStampParam) (PostStampResponse, error) {
+	return DefaultClient.PostStamp(ctx, param)
+}
+
+// PostStamp 打刻
+func (c *Client) PostStamp(ctx context.Context, param PostStampParam) (PostStampResponse, error) {
 	if param.LoginCompanyCode == "" {
 		return PostStampResponse{}, errors.New("LoginCompanyCode must be set")
 	}
-	if param.Token == "" {
+	if param.Token == "" && c.tokens == nil {
 		return PostStampResponse{}, errors.New("Token must be set")
 	}
+	if param.Timezone != "" {
+		if _, err := ParseTimezone(param.Timezone); err != nil {
+			return PostStampResponse{}, errors.New("Timezone must be in ±HH:MM format")
+		}
+	}
+	var key string
+	if param.DedupeWindow > 0 {
+		if param.Type == StampTypeUnknown {
+			return PostStampResponse{}, errors.New("Type must be set when DedupeWindow is set")
+		}
+		dup, k, err := c.findDuplicateStamp(ctx, param)
+		if err != nil {
+			return PostStampResponse{}, err
+		}
+		if dup != nil {
+			return *dup, nil
+		}
+		key = k
+	}
 
-	endpoint := fmt.Sprintf("/%s/stamps", param.LoginCompanyCode)
+	path := fmt.Sprintf("/%s/stamps", param.LoginCompanyCode)
 
-	cli := NewClient(param.LoginCompanyCode, param.Token)
-	res, err := cli.Post(ctx, endpoint, param)
-	if err != nil {
+	var res PostStampResponse
+	r := &request{method: http.MethodPost, path: path, body: param, token: param.Token, idempotent: param.Retryable}
+	if err := c.call(ctx, r, &res); err != nil {
 		return PostStampResponse{}, err
 	}
-	if res.StatusCode != http.StatusOK {
-		return PostStampResponse{}, fmt.Errorf("Status code=%d", res.StatusCode)
-	}
+	res.DedupeKey = key
+	return res, nil
+}
 
-	var psr struct {
-		Success  bool              `json:"success"`
-		Response PostStampResponse `json:"response"`
-		Errors   []Error           `json:"errors"`
+// findDuplicateStamp 打刻日時(未指定の場合はクライアントの現在時刻)の前後DedupeWindow以内に打刻した同じ種別の打刻を探す
+// 打刻した時刻はStamp.ClientTimeで比較する。ない場合はnilと打刻する従業員のDedupeKeyを返す
+func (c *Client) findDuplicateStamp(ctx context.Context, param PostStampParam) (*PostStampResponse, string, error) {
+	now := c.now()
+	at := now
+	if param.StampedAt != nil && !param.StampedAt.IsZero() {
+		at = param.StampedAt.Time
 	}
-
-	if err := json.NewDecoder(res.Body).Decode(&psr); err != nil {
-		return PostStampResponse{}, err
+	// 過去の日時を指定した打刻はサーバでは後から記録されているので現在時刻まで探す
+	end := at.Add(param.DedupeWindow)
+	if now.After(end) {
+		end = now
+	}
+	stamps, err := c.GetStamps(ctx, GetStampParam{
+		LoginCompanyCode: param.LoginCompanyCode,
+		Token:            param.Token,
+		StartDate:        at.Add(-param.DedupeWindow),
+		EndDate:          end,
+	})
+	if err != nil {
+		return nil, "", err
 	}
-	if !psr.Success {
-		return PostStampResponse{}, errors.New("Requesting Stamp API failed")
+	key := DedupeKey(param.LoginCompanyCode, stamps.StaffID, param.Type)
+	for i := len(stamps.Stamps) - 1; i >= 0; i-- {
+		s := stamps.Stamps[i]
+		t := s.ClientTime()
+		if t.IsZero() || s.Type != param.Type {
+			continue
+		}
+		if d := t.Sub(at); d < -param.DedupeWindow || d > param.DedupeWindow {
+			continue
+		}
+		return &PostStampResponse{
+			LoginCompanyCode: stamps.LoginCompanyCode,
+			StaffID:          stamps.StaffID,
+			Type:             s.Type,
+			StampedAt:        s.StampedAt,
+			Duplicate:        true,
+			DedupeKey:        key,
+		}, key, nil
+	}
+	return nil, key, nil
+}
+
+// postStampBody 打刻リクエストの本文
+type postStampBody struct {
+	Token     string    `json:"token"`
+	Type      StampType `json:"type,omitempty"`
+	StampedAt string    `json:"stampedAt,omitempty"`
+	Timezone  string    `json:"timezone,omitempty"`
+}
+
+// withToken StampedAtはTimezoneが指定されている場合はそのタイムゾーン、それ以外はLocationの壁時計時刻で送る
+func (p PostStampParam) withToken(token string) interface{} {
+	b := postStampBody{Token: token, Type: p.Type, Timezone: p.Timezone}
+	if p.StampedAt != nil && !p.StampedAt.IsZero() {
+		loc := location()
+		if p.Timezone != "" {
+			if tz, err := ParseTimezone(p.Timezone); err == nil {
+				loc = tz
+			}
+		}
+		b.StampedAt = p.StampedAt.In(loc).Format(ReturnDateFormat)
 	}
-	return psr.Response, nil
+	return b
 }
@@ -0,0 +1,104 @@
+package akashi
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+var (
+	// DateFormat yyyymmddHHMMSS形式
+	DateFormat = "20060102150405"
+	// ReturnDateFormat yyyy/mm/dd HH:MM:SS形式
+	ReturnDateFormat = "2006/01/02 15:04:05"
+)
+
+// Location AKASHIの日時を解釈・出力するタイムゾーン(デフォルトはAsia/Tokyo)
+var Location = loadLocation("Asia/Tokyo", "JST", 9*60*60)
+
+// loadLocation タイムゾーンを読み込む
+// タイムゾーンデータベースが無い環境では固定オフセットで代用する
+func loadLocation(name, abbr string, offset int) *time.Location {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return time.FixedZone(abbr, offset)
+	}
+	return loc
+}
+
+func location() *time.Location {
+	if Location == nil {
+		return time.Local
+	}
+	return Location
+}
+
+// ParseTimezone ±HH:MMまたは±HHMM形式のタイムゾーンを固定オフセットのタイムゾーンにする
+func ParseTimezone(s string) (*time.Location, error) {
+	for _, layout := range []string{"-07:00", "-0700"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			_, offset := t.Zone()
+			return time.FixedZone(s, offset), nil
+		}
+	}
+	return nil, fmt.Errorf("akashi: cannot parse %q as timezone (±HH:MM)", s)
+}
+
+// FormatTimezone tのタイムゾーンのオフセットを±HH:MM形式で表す
+func FormatTimezone(t time.Time) string {
+	return t.Format("-07:00")
+}
+
+// AkTime 時間
+//
+// JSONではLocationの壁時計時刻をReturnDateFormat形式で表す
+type AkTime struct {
+	time.Time
+}
+
+// NewAkTime time.TimeからAkTimeを生成する
+func NewAkTime(t time.Time) *AkTime {
+	return &AkTime{t}
+}
+
+// ParseAkTime ReturnDateFormatまたはDateFormat形式の日時をLocationで解釈する
+func ParseAkTime(s string) (time.Time, error) {
+	for _, layout := range []string{ReturnDateFormat, DateFormat} {
+		if t, err := time.ParseInLocation(layout, s, location()); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("akashi: cannot parse %q as %q or %q", s, ReturnDateFormat, DateFormat)
+}
+
+// MarshalJSON time.MarshalJSONの拡張
+// ゼロ値はnullになる
+func (a AkTime) MarshalJSON() ([]byte, error) {
+	if a.IsZero() {
+		return []byte("null"), nil
+	}
+	return json.Marshal(a.In(location()).Format(ReturnDateFormat))
+}
+
+// UnmarshalJSON time.UnmarshalJSONの拡張
+// nullと空文字列はゼロ値になる
+func (a *AkTime) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*a = AkTime{}
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	if s == "" {
+		*a = AkTime{}
+		return nil
+	}
+	t, err := ParseAkTime(s)
+	if err != nil {
+		return err
+	}
+	*a = AkTime{t}
+	return nil
+}
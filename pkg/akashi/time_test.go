@@ -0,0 +1,131 @@
+package akashi
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAkTimeUnmarshalJSON(t *testing.T) {
+	tests := map[string]struct {
+		data string
+		want time.Time
+		err  bool
+	}{
+		"return date format": {
+			data: `"2026/09/01 09:05:30"`,
+			want: time.Date(2026, 9, 1, 9, 5, 30, 0, Location),
+		},
+		"date format": {
+			data: `"20260901090530"`,
+			want: time.Date(2026, 9, 1, 9, 5, 30, 0, Location),
+		},
+		"null": {
+			data: `null`,
+		},
+		"empty": {
+			data: `""`,
+		},
+		"rfc3339": {
+			data: `"2026-09-01T09:05:30+09:00"`,
+			err:  true,
+		},
+		"not a string": {
+			data: `20260901090530`,
+			err:  true,
+		},
+	}
+	for scenario, test := range tests {
+		a := AkTime{time.Now()}
+		err := json.Unmarshal([]byte(test.data), &a)
+		if test.err {
+			assert.Error(t, err, scenario)
+			continue
+		}
+		assert.NoError(t, err, scenario)
+		assert.True(t, test.want.Equal(a.Time), "%s: %v", scenario, a.Time)
+	}
+}
+
+func TestAkTimeMarshalJSON(t *testing.T) {
+	utc := time.Date(2026, 9, 1, 0, 5, 30, 0, time.UTC)
+	tests := map[string]struct {
+		in   interface{}
+		want string
+	}{
+		"jst":     {in: AkTime{time.Date(2026, 9, 1, 9, 5, 30, 0, Location)}, want: `"2026/09/01 09:05:30"`},
+		"utc":     {in: AkTime{utc}, want: `"2026/09/01 09:05:30"`},
+		"pointer": {in: NewAkTime(utc), want: `"2026/09/01 09:05:30"`},
+		"zero":    {in: AkTime{}, want: `null`},
+		"omitempty": {
+			in:   PostStampParam{Token: "t", StampedAt: NewAkTime(utc)},
+			want: `{"token":"t","stampedAt":"2026/09/01 09:05:30"}`,
+		},
+	}
+	for scenario, test := range tests {
+		b, err := json.Marshal(test.in)
+		assert.NoError(t, err, scenario)
+		assert.Equal(t, test.want, string(b), scenario)
+	}
+}
+
+func TestAkTimeRoundTrip(t *testing.T) {
+	locs := []*time.Location{time.UTC, Location, time.FixedZone("PST", -8*60*60)}
+	for _, loc := range locs {
+		in := AkTime{time.Date(2026, 12, 31, 23, 59, 59, 0, loc)}
+		b, err := json.Marshal(in)
+		assert.NoError(t, err)
+		var out AkTime
+		assert.NoError(t, json.Unmarshal(b, &out))
+		assert.True(t, in.Equal(out.Time), "%v != %v", in.Time, out.Time)
+	}
+}
+
+func TestAkTimeLocation(t *testing.T) {
+	orig := Location
+	defer func() { Location = orig }()
+	Location = time.UTC
+
+	var a AkTime
+	assert.NoError(t, json.Unmarshal([]byte(`"2026/09/01 09:00:00"`), &a))
+	assert.Equal(t, time.Date(2026, 9, 1, 9, 0, 0, 0, time.UTC), a.Time)
+}
+
+func TestParseTimezone(t *testing.T) {
+	for _, s := range []string{"+09:00", "+0900"} {
+		loc, err := ParseTimezone(s)
+		if assert.NoError(t, err, s) {
+			_, offset := time.Date(2026, 9, 1, 0, 0, 0, 0, loc).Zone()
+			assert.Equal(t, 9*60*60, offset, s)
+		}
+	}
+	loc, err := ParseTimezone("-05:30")
+	assert.NoError(t, err)
+	assert.Equal(t, "-05:30", FormatTimezone(time.Date(2026, 9, 1, 0, 0, 0, 0, loc)))
+	_, err = ParseTimezone("Asia/Tokyo")
+	assert.Error(t, err)
+}
+
+func TestPostStampBody(t *testing.T) {
+	at := NewAkTime(time.Date(2026, 9, 1, 0, 5, 30, 0, time.UTC))
+	tests := map[string]struct {
+		in   PostStampParam
+		want string
+	}{
+		"location": {
+			in:   PostStampParam{Type: StampTypeGoToWork, StampedAt: at},
+			want: `{"token":"t","type":11,"stampedAt":"2026/09/01 09:05:30"}`,
+		},
+		"timezone": {
+			in:   PostStampParam{StampedAt: at, Timezone: "-04:00"},
+			want: `{"token":"t","stampedAt":"2026/08/31 20:05:30","timezone":"-04:00"}`,
+		},
+	}
+	for scenario, test := range tests {
+		b, err := json.Marshal(test.in.withToken("t"))
+		assert.NoError(t, err, scenario)
+		assert.Equal(t, test.want, string(b), scenario)
+	}
+}
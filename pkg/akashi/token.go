@@ -2,7 +2,6 @@ package akashi
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
@@ -10,7 +9,7 @@ import (
 
 // PostTokenReissueParam トークン再発行リクエストパラメータ
 type PostTokenReissueParam struct {
-	LoginCompanyCode string // AKASHI企業ID
+	LoginCompanyCode string `json:"-"`     // AKASHI企業ID
 	Token            string `json:"token"` // アクセストークン
 }
 
@@ -24,7 +23,14 @@ type PostTokenReissueResponse struct {
 }
 
 // PostTokenReissue トークン再発行
+// DefaultClientを利用する
 func PostTokenReissue(ctx context.Context, param PostTokenReissueParam) (PostTokenReissueResponse, error) {
+	return DefaultClient.PostTokenReissue(ctx, param)
+}
+
+// PostTokenReissue トークン再発行
+// WithTokenSourceの指定に関わらずparam.Tokenを再発行する
+func (c *Client) PostTokenReissue(ctx context.Context, param PostTokenReissueParam) (PostTokenReissueResponse, error) {
 	if param.LoginCompanyCode == "" {
 		return PostTokenReissueResponse{}, errors.New("LoginCompanyCode must be set")
 	}
@@ -32,28 +38,17 @@ func PostTokenReissue(ctx context.Context, param PostTokenReissueParam) (PostTok
 		return PostTokenReissueResponse{}, errors.New("Token must be set")
 	}
 
-	endpoint := fmt.Sprintf("/token/reissue/%s", param.LoginCompanyCode)
+	path := fmt.Sprintf("/token/reissue/%s", param.LoginCompanyCode)
 
-	cli := NewClient(param.LoginCompanyCode, param.Token)
-	res, err := cli.Post(ctx, endpoint, param)
-	if err != nil {
+	var res PostTokenReissueResponse
+	r := &request{method: http.MethodPost, path: path, body: param, token: param.Token, noTokenSource: true}
+	if err := c.call(ctx, r, &res); err != nil {
 		return PostTokenReissueResponse{}, err
 	}
-	if res.StatusCode != http.StatusOK {
-		return PostTokenReissueResponse{}, fmt.Errorf("Status code=%d", res.StatusCode)
-	}
-
-	var ptr struct {
-		Success  bool                     `json:"success"`
-		Response PostTokenReissueResponse `json:"response"`
-		Errors   []Error                  `json:"errors"`
-	}
+	return res, nil
+}
 
-	if err := json.NewDecoder(res.Body).Decode(&ptr); err != nil {
-		return PostTokenReissueResponse{}, err
-	}
-	if !ptr.Success {
-		return PostTokenReissueResponse{}, errors.New("Requesting Token Reissue API failed")
-	}
-	return ptr.Response, nil
+func (p PostTokenReissueParam) withToken(token string) interface{} {
+	p.Token = token
+	return p
 }
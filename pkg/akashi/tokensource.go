@@ -0,0 +1,145 @@
+package akashi
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DefaultRefreshBefore 有効期限のどれだけ前にトークンを再発行するか
+const DefaultRefreshBefore = 10 * time.Minute
+
+// Token アクセストークンと有効期限
+type Token struct {
+	Value     string    // アクセストークン
+	ExpiredAt time.Time // 有効期限(ゼロ値の場合は不明)
+}
+
+// TokenSource リクエストに利用するアクセストークンを提供する
+type TokenSource interface {
+	// Token 有効なアクセストークンを返す
+	Token(ctx context.Context) (string, error)
+	// Refresh expiredが期限切れと判定された場合に新しいアクセストークンを返す
+	Refresh(ctx context.Context, expired string) (string, error)
+}
+
+// staticTokenSource 常に同じトークンを返すTokenSource
+type staticTokenSource string
+
+// StaticTokenSource 常に同じトークンを返すTokenSourceを生成する
+func StaticTokenSource(token string) TokenSource {
+	return staticTokenSource(token)
+}
+
+func (s staticTokenSource) Token(context.Context) (string, error) {
+	return string(s), nil
+}
+
+func (s staticTokenSource) Refresh(context.Context, string) (string, error) {
+	return "", ErrTokenExpired
+}
+
+// ReissueTokenSource 有効期限が近づいたトークンを自動的に再発行するTokenSource
+//
+// 複数のgoroutineから同時に利用でき、再発行は同時に1回しか行われない
+type ReissueTokenSource struct {
+	// RefreshBefore 有効期限のどれだけ前に再発行するか(0の場合はDefaultRefreshBefore)
+	RefreshBefore time.Duration
+	// OnReissue 再発行した時に呼び出される(新しいトークンの保存に利用する)
+	OnReissue func(PostTokenReissueResponse) error
+	// OnSaveError OnReissueが失敗した時に呼び出される
+	// 新しいトークンでリクエストは続けるので、保存の失敗はここかクライアントのLoggerで知らせる
+	OnSaveError func(error)
+
+	c           *Client
+	companyCode string
+	now         func() time.Time
+
+	mu  sync.Mutex
+	tok Token
+}
+
+// NewReissueTokenSource ReissueTokenSourceを生成する
+// 再発行にはcを利用する
+func NewReissueTokenSource(c *Client, companyCode string, tok Token) *ReissueTokenSource {
+	return &ReissueTokenSource{
+		c:           c,
+		companyCode: companyCode,
+		now:         time.Now,
+		tok:         tok,
+	}
+}
+
+// Current 現在のトークンを返す
+func (s *ReissueTokenSource) Current() Token {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.tok
+}
+
+// Token 有効なアクセストークンを返す
+// 有効期限が近い場合は再発行する。再発行に失敗しても有効期限前であれば現在のトークンを返す
+func (s *ReissueTokenSource) Token(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.expiring() {
+		if err := s.reissue(ctx); err != nil {
+			if !s.now().Before(s.tok.ExpiredAt) {
+				return "", err
+			}
+			s.c.logger.Log(LevelWarn, "reissuing token failed, using the current token", "expired_at", s.tok.ExpiredAt, "error", err)
+		}
+	}
+	return s.tok.Value, nil
+}
+
+// Refresh expiredが現在のトークンであれば再発行する
+// 他のgoroutineが既に再発行している場合は再発行済みのトークンを返す
+func (s *ReissueTokenSource) Refresh(ctx context.Context, expired string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.tok.Value == expired {
+		if err := s.reissue(ctx); err != nil {
+			return "", err
+		}
+	}
+	return s.tok.Value, nil
+}
+
+func (s *ReissueTokenSource) expiring() bool {
+	if s.tok.ExpiredAt.IsZero() {
+		return false
+	}
+	before := s.RefreshBefore
+	if before <= 0 {
+		before = DefaultRefreshBefore
+	}
+	return !s.now().Before(s.tok.ExpiredAt.Add(-before))
+}
+
+// reissue トークンを再発行する(s.muを保持して呼び出す)
+func (s *ReissueTokenSource) reissue(ctx context.Context) error {
+	res, err := s.c.PostTokenReissue(ctx, PostTokenReissueParam{
+		LoginCompanyCode: s.companyCode,
+		Token:            s.tok.Value,
+	})
+	if err != nil {
+		return fmt.Errorf("akashi: reissuing token: %w", err)
+	}
+	s.tok = Token{Value: res.Token}
+	if res.ExpiredAt != nil {
+		s.tok.ExpiredAt = res.ExpiredAt.Time
+	}
+	if s.OnReissue != nil {
+		// 旧トークンは既に無効なので、保存に失敗しても新しいトークンでリクエストを続ける
+		if err := s.OnReissue(res); err != nil {
+			err = fmt.Errorf("akashi: saving reissued token: %w", err)
+			s.c.logger.Log(LevelError, "saving reissued token failed", "error", err)
+			if s.OnSaveError != nil {
+				s.OnSaveError(err)
+			}
+		}
+	}
+	return nil
+}
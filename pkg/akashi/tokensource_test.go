@@ -0,0 +1,165 @@
+package akashi_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"hapoon/go-akashi/pkg/akashi"
+	"hapoon/go-akashi/pkg/akashi/akashitest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// reissueCount トークン再発行のリクエスト数
+func reissueCount(srv *akashitest.Server) int {
+	n := 0
+	for _, r := range srv.Requests() {
+		if r.Method == http.MethodPost && strings.HasPrefix(r.Path, "/token/reissue/") {
+			n++
+		}
+	}
+	return n
+}
+
+func TestReissueTokenSourceBeforeExpiry(t *testing.T) {
+	srv := newTestServer()
+	defer srv.Close()
+	srv.AddToken(testCompanyCode, "expiring", testStaffID, time.Now().Add(5*time.Minute))
+
+	cli := srv.Client()
+	ts := akashi.NewReissueTokenSource(cli, testCompanyCode, akashi.Token{Value: "expiring", ExpiredAt: time.Now().Add(5 * time.Minute)})
+	var saved []akashi.PostTokenReissueResponse
+	ts.OnReissue = func(res akashi.PostTokenReissueResponse) error {
+		saved = append(saved, res)
+		return nil
+	}
+	cli = srv.Client(akashi.WithTokenSource(ts))
+
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		_, err := cli.GetAlerts(ctx, akashi.GetAlertParam{LoginCompanyCode: testCompanyCode})
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, 1, reissueCount(srv))
+	if assert.Len(t, saved, 1) {
+		assert.Equal(t, saved[0].Token, ts.Current().Value)
+		assert.True(t, saved[0].ExpiredAt.Equal(ts.Current().ExpiredAt))
+	}
+}
+
+func TestReissueTokenSourceAfterExpiredError(t *testing.T) {
+	srv := newTestServer()
+	defer srv.Close()
+	// サーバ側では期限切れだがクライアントは有効期限を知らない
+	srv.AddToken(testCompanyCode, "expired", testStaffID, time.Now().Add(time.Minute))
+	srv.Now = func() time.Time { return time.Now().Add(time.Hour) }
+
+	ts := akashi.NewReissueTokenSource(srv.Client(), testCompanyCode, akashi.Token{Value: "expired"})
+	cli := srv.Client(akashi.WithTokenSource(ts))
+	_, err := cli.PostStamp(context.Background(), akashi.PostStampParam{LoginCompanyCode: testCompanyCode, Type: akashi.StampTypeGoToWork})
+	assert.Error(t, err, "reissue with an expired token fails")
+	assert.Len(t, srv.Stamps(testCompanyCode, testStaffID), 0)
+
+	// 再発行できる場合は新しいトークンで1回だけ再送する
+	srv.Now = time.Now
+	srv.AddToken(testCompanyCode, "stale", testStaffID, time.Time{})
+	srv.InjectFault(akashitest.Fault{Kind: akashitest.FaultFailure, Path: "/abc/stamps", Times: 1, Code: "TOKEN_EXPIRED", Message: "token expired"})
+	ts = akashi.NewReissueTokenSource(srv.Client(), testCompanyCode, akashi.Token{Value: "stale"})
+	cli = srv.Client(akashi.WithTokenSource(ts))
+	res, err := cli.PostStamp(context.Background(), akashi.PostStampParam{LoginCompanyCode: testCompanyCode, Type: akashi.StampTypeGoToWork})
+	assert.NoError(t, err)
+	assert.Equal(t, akashi.StampTypeGoToWork, res.Type)
+	assert.NotEqual(t, "stale", ts.Current().Value)
+	assert.Len(t, srv.Stamps(testCompanyCode, testStaffID), 1)
+
+	// エラーコードから期限切れか判断できない401でも再発行して再送する
+	srv.AddToken(testCompanyCode, "unknown", testStaffID, time.Time{})
+	srv.InjectFault(akashitest.Fault{Kind: akashitest.FaultUnauthorized, Path: "/abc/stamps", Times: 1, Code: "E401", Message: "認証に失敗しました"})
+	ts = akashi.NewReissueTokenSource(srv.Client(), testCompanyCode, akashi.Token{Value: "unknown"})
+	cli = srv.Client(akashi.WithTokenSource(ts))
+	_, err = cli.PostStamp(context.Background(), akashi.PostStampParam{LoginCompanyCode: testCompanyCode, Type: akashi.StampTypeLeaveWork})
+	assert.NoError(t, err)
+	assert.NotEqual(t, "unknown", ts.Current().Value)
+	assert.Len(t, srv.Stamps(testCompanyCode, testStaffID), 2)
+}
+
+func TestReissueTokenSourceConcurrent(t *testing.T) {
+	srv := newTestServer()
+	defer srv.Close()
+	srv.AddToken(testCompanyCode, "expiring", testStaffID, time.Time{})
+
+	ts := akashi.NewReissueTokenSource(srv.Client(), testCompanyCode, akashi.Token{Value: "expiring", ExpiredAt: time.Now()})
+	cli := srv.Client(akashi.WithTokenSource(ts))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := cli.GetAlerts(context.Background(), akashi.GetAlertParam{LoginCompanyCode: testCompanyCode})
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 1, reissueCount(srv))
+}
+
+func TestReissueTokenSourceSaveError(t *testing.T) {
+	srv := newTestServer()
+	defer srv.Close()
+	srv.AddToken(testCompanyCode, "expiring", testStaffID, time.Time{})
+
+	ts := akashi.NewReissueTokenSource(srv.Client(), testCompanyCode, akashi.Token{Value: "expiring", ExpiredAt: time.Now()})
+	saveErr := errors.New("disk full")
+	ts.OnReissue = func(akashi.PostTokenReissueResponse) error { return saveErr }
+	var reported []error
+	ts.OnSaveError = func(err error) { reported = append(reported, err) }
+	cli := srv.Client(akashi.WithTokenSource(ts))
+
+	// 保存に失敗してもリクエストは新しいトークンで完了する
+	_, err := cli.GetAlerts(context.Background(), akashi.GetAlertParam{LoginCompanyCode: testCompanyCode})
+	assert.NoError(t, err)
+	if assert.Len(t, reported, 1) {
+		assert.True(t, errors.Is(reported[0], saveErr))
+	}
+	assert.NotEqual(t, "expiring", ts.Current().Value)
+	_, err = cli.GetAlerts(context.Background(), akashi.GetAlertParam{LoginCompanyCode: testCompanyCode})
+	assert.NoError(t, err)
+}
+
+func TestReissueTokenSourceReissueError(t *testing.T) {
+	srv := newTestServer()
+	defer srv.Close()
+	srv.AddToken(testCompanyCode, "expiring", testStaffID, time.Time{})
+	srv.InjectFault(akashitest.Fault{Kind: akashitest.FaultFailure, Path: "/token/reissue/"})
+
+	// 再発行に失敗しても有効期限前であれば現在のトークンでリクエストする
+	ts := akashi.NewReissueTokenSource(srv.Client(), testCompanyCode, akashi.Token{Value: "expiring", ExpiredAt: time.Now().Add(5 * time.Minute)})
+	cli := srv.Client(akashi.WithTokenSource(ts))
+	for i := 0; i < 2; i++ {
+		_, err := cli.GetAlerts(context.Background(), akashi.GetAlertParam{LoginCompanyCode: testCompanyCode})
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, 2, reissueCount(srv), "reissue is retried on each request")
+	assert.Equal(t, "expiring", ts.Current().Value)
+
+	// 有効期限を過ぎている場合は再発行のエラーを返す
+	ts = akashi.NewReissueTokenSource(srv.Client(), testCompanyCode, akashi.Token{Value: "expiring", ExpiredAt: time.Now().Add(-time.Minute)})
+	cli = srv.Client(akashi.WithTokenSource(ts))
+	_, err := cli.GetAlerts(context.Background(), akashi.GetAlertParam{LoginCompanyCode: testCompanyCode})
+	assert.True(t, errors.Is(err, akashi.ErrValidation), err)
+}
+
+func TestStaticTokenSource(t *testing.T) {
+	srv := newTestServer()
+	defer srv.Close()
+
+	cli := srv.Client(akashi.WithTokenSource(akashi.StaticTokenSource(testToken)))
+	_, err := cli.GetAlerts(context.Background(), akashi.GetAlertParam{LoginCompanyCode: testCompanyCode, Token: "ignored"})
+	assert.NoError(t, err)
+}